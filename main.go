@@ -12,11 +12,15 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/bwplotka/mdox/pkg/apidoc"
 	"github.com/bwplotka/mdox/pkg/cache"
 	"github.com/bwplotka/mdox/pkg/clilog"
 	"github.com/bwplotka/mdox/pkg/extkingpin"
@@ -25,6 +29,7 @@ import (
 	"github.com/bwplotka/mdox/pkg/mdformatter/mdgen"
 	"github.com/bwplotka/mdox/pkg/transform"
 	"github.com/bwplotka/mdox/pkg/version"
+	"github.com/bwplotka/mdox/pkg/yamlgen"
 	"github.com/charmbracelet/glamour"
 	"github.com/efficientgo/core/errcapture"
 	"github.com/efficientgo/core/logerrcapture"
@@ -41,8 +46,6 @@ const (
 	logFormatLogfmt = "logfmt"
 	logFormatJson   = "json"
 	logFormatCLILog = "clilog"
-
-	cacheFile = ".mdoxcache"
 )
 
 func setupLogger(logLevel, logFormat string) log.Logger {
@@ -80,10 +83,16 @@ func main() {
 	// Profiling and metrics.
 	profilesPath := app.Flag("profiles.path", "Path to directory where CPU and heap profiles will be saved; If empty, no profiling will be enabled.").ExistingDir()
 	metricsPath := app.Flag("metrics.path", "Path to directory where metrics are saved in OpenMetrics format; If empty, no metrics will be saved.").ExistingDir()
+	cachePath := app.Flag("cache.path", "Path to the link check cache. The file cache type appends .json to it.").Default(".mdoxcache").String()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	registerFmt(ctx, app, metricsPath)
+	registerFmt(ctx, app, metricsPath, cachePath)
 	registerTransform(ctx, app)
+	registerSchema(ctx, app)
+	registerCfgTable(ctx, app)
+	registerAPIDoc(ctx, app)
+	registerCache(ctx, app, cachePath)
+	registerLinks(ctx, app, cachePath)
 
 	cmd, runner := app.Parse()
 	logger := setupLogger(*logLevel, *logFormat)
@@ -197,7 +206,7 @@ func interrupt(logger log.Logger, cancel <-chan struct{}) error {
 	}
 }
 
-func registerFmt(_ context.Context, app *extkingpin.App, metricsPath *string) {
+func registerFmt(_ context.Context, app *extkingpin.App, metricsPath, cachePath *string) {
 	cmd := app.Command("fmt", "Formats in-place given markdown files uniformly following GFM (GitHub Flavored Markdown: https://github.github.com/gfm/). Example: mdox fmt *.md")
 	files := cmd.Arg("files", "Markdown file(s) to process.").Required().ExistingFiles()
 	checkOnly := cmd.Flag("check", "If true, fmt will not modify the given files, instead it will fail if files needs formatting").Bool()
@@ -210,9 +219,23 @@ This directive runs executable with arguments and put its stderr and stdout outp
 	anchorDir := cmd.Flag("anchor-dir", "Anchor directory for all transformers. PWD is used if flag is not specified.").ExistingDir()
 	linksLocalizeForAddress := cmd.Flag("links.localize.address-regex", "If specified, all HTTP(s) links that target a domain and path matching given regexp will be transformed to relative to anchor dir path (if exists). "+
 		"Absolute path links will be converted to relative links to anchor dir as well.").Regexp()
+	linksLocalizeConfig := extflag.RegisterPathOrContent(cmd, "links.localize.config", "YAML file with localization targets, mapping address regexps to local directories relative to anchor dir, evaluated in order. Alternative to --links.localize.address-regex for multi-version or multi-domain docs, with spec defined in github.com/bwplotka/mdox/pkg/linktransformer.LocalizeConfig", extflag.WithEnvSubstitution())
+	linksGlobalizeBase := cmd.Flag("links.globalize.base", "If specified, all relative links (and links absolute to anchor dir) will be transformed to absolute URLs, by resolving path of linked file relative to anchor dir against this base URL e.g. https://github.com/bwplotka/mdox/blob/main/. "+
+		"Useful for publishing files elsewhere e.g. on pkg.go.dev. Requires --links.globalize.output.").String()
+	linksGlobalizeExtensions := cmd.Flag("links.globalize.extension", "Replacement of linked file extension in globalized links, e.g. '.md=.html' or '.md=/' for websites. Can be specified multiple times.").PlaceHolder("EXT=REPLACEMENT").StringMap()
+	linksGlobalizeOutput := cmd.Flag("links.globalize.output", "File the formatted single input file with globalized links is written into, instead of in-place.").String()
 	linksValidateEnabled := cmd.Flag("links.validate", "If true, all links will be validated").Short('l').Bool()
 	linksValidateConfig := extflag.RegisterPathOrContent(cmd, "links.validate.config", "YAML file for skipping link check, with spec defined in github.com/bwplotka/mdox/pkg/linktransformer.ValidatorConfig", extflag.WithEnvSubstitution())
 
+	linksFixRedirects := cmd.Flag("links.fix-redirects", "If true, links permanently redirected (301, 308) will be replaced with their final URL. Requires --links.validate. All files are validated first, so remote links are checked before rewriting.").Bool()
+	linksRewriteConfig := extflag.RegisterPathOrContent(cmd, "links.rewrite.config", "YAML file with link rewrite rules (regex and replacement with capture groups), applied before validation and localization, with spec defined in github.com/bwplotka/mdox/pkg/linktransformer.RewriteConfig", extflag.WithEnvSubstitution())
+	linksFixMoved := cmd.Flag("links.fix-moved", "If true, relative links to files or directories which do not exist, but were moved or renamed according to git history of anchor dir, will be rewritten to their new location. Links to files moved to multiple locations are reported as errors.").Bool()
+	linksMode := cmd.Flag("links.mode", "Link validation mode. 'full' checks all links. 'local-only' checks only relative links and anchors, without network access. 'cache-only' takes results of remote links from cache (configured in --links.validate.config) and reports links without cached result as unverified, without failing. Emails are checked only syntactically in offline modes. Requires --links.validate.").
+		Default(string(linktransformer.ModeFull)).Enum(string(linktransformer.ModeFull), string(linktransformer.ModeLocalOnly), string(linktransformer.ModeCacheOnly))
+
+	linksFailOn := cmd.Flag("links.fail-on", "Lowest severity of failed links which fails validation. With 'error', failed links of validators with 'warn' severity (set in --links.validate.config) are only reported as warnings. With 'warn', all failed links fail validation. Requires --links.validate.").
+		Default(string(linktransformer.SeverityError)).Enum(string(linktransformer.SeverityError), string(linktransformer.SeverityWarn))
+
 	clearCache := cmd.Flag("cache.clear", "If true, entire cache database will be dropped and rebuilt when mdox is run. Useful in case cache needs to be cleared immediately from GitHub Actions or other CI runner cache.").Bool()
 
 	cmd.Run(func(ctx context.Context, logger log.Logger) (err error) {
@@ -223,7 +246,7 @@ This directive runs executable with arguments and put its stderr and stdout outp
 
 		var opts []mdformatter.Option
 		if !*disableGenCodeBlocksDirectives {
-			opts = append(opts, mdformatter.WithCodeBlockTransformer(mdgen.NewCodeBlockTransformer()))
+			opts = append(opts, mdformatter.WithCodeBlockTransformer(mdgen.NewCodeBlockTransformer()), mdformatter.WithGenSectionTransformer(mdgen.NewGenSectionTransformer()))
 		}
 		if *softWraps {
 			opts = append(opts, mdformatter.WithSoftWraps())
@@ -247,34 +270,120 @@ This directive runs executable with arguments and put its stderr and stdout outp
 			return err
 		}
 
-		var linkTr []mdformatter.LinkTransformer
-		if *linksValidateEnabled {
-			var storage *cache.SQLite3Storage
+		if *linksFixRedirects && !*linksValidateEnabled {
+			return errors.New("--links.fix-redirects requires --links.validate")
+		}
+		if linktransformer.Mode(*linksMode) != linktransformer.ModeFull && !*linksValidateEnabled {
+			return errors.New("--links.mode requires --links.validate")
+		}
+		if linktransformer.Severity(*linksFailOn) != linktransformer.SeverityError && !*linksValidateEnabled {
+			return errors.New("--links.fail-on requires --links.validate")
+		}
+		if (*linksGlobalizeBase == "") != (*linksGlobalizeOutput == "") {
+			return errors.New("--links.globalize.base and --links.globalize.output have to be specified together")
+		}
+		if *linksGlobalizeBase != "" {
+			if len(*files) != 1 {
+				return errors.New("--links.globalize.output requires single file to format")
+			}
+			if *checkOnly {
+				return errors.New("--links.globalize.base can't be used with --check, as files are not modified in-place")
+			}
+		}
 
-			validateConfigContent, err := linksValidateConfig.Content()
+		var (
+			linkTr   []mdformatter.LinkTransformer
+			warnings = &linktransformer.Warnings{}
+		)
+		// Logged at the end of command, so warnings of all files are summarized next to errors.
+		defer warnings.Log(logger)
+
+		linksRewriteConfigContent, err := linksRewriteConfig.Content()
+		if err != nil {
+			return err
+		}
+		if len(linksRewriteConfigContent) > 0 {
+			// First in chain, so rewritten links are fixed, validated and localized.
+			r, err := linktransformer.NewRewriter(logger, linksRewriteConfigContent)
 			if err != nil {
 				return err
 			}
-
-			storage = &cache.SQLite3Storage{
-				Filename:   cacheFile,
-				ClearCache: *clearCache,
+			linkTr = append(linkTr, r)
+		}
+		if *linksFixMoved {
+			// Before validator, so rewritten links are validated and localized.
+			f, err := linktransformer.NewMovedFixer(ctx, logger, anchorDir)
+			if err != nil {
+				return err
+			}
+			linkTr = append(linkTr, f)
+		}
+		if *linksValidateEnabled {
+			var validateConfigContent []byte
+			validateConfigContent, err = linksValidateConfig.Content()
+			if err != nil {
+				return err
 			}
 
-			v, err := linktransformer.NewValidator(ctx, logger, validateConfigContent, anchorDir, storage, reg)
+			storage := cache.NewStorage(*cachePath, *clearCache)
+			// Closed at the end of command, as cache file backend persists cache on Close.
+			defer errcapture.Do(&err, storage.Close, "close cache")
+
+			validatorOpts := []linktransformer.ValidatorOption{
+				linktransformer.WithMode(linktransformer.Mode(*linksMode)),
+				linktransformer.WithFailOn(linktransformer.Severity(*linksFailOn)),
+				linktransformer.WithWarnings(warnings),
+			}
+			if *linksFixRedirects {
+				validatorOpts = append(validatorOpts, linktransformer.WithFixRedirects())
+			}
+			var v mdformatter.LinkTransformer
+			v, err = linktransformer.NewValidator(ctx, logger, validateConfigContent, anchorDir, storage, reg, validatorOpts...)
 			if err != nil {
 				return err
 			}
 			linkTr = append(linkTr, v)
 		}
+		linksLocalizeConfigContent, err := linksLocalizeConfig.Content()
+		if err != nil {
+			return err
+		}
+		if *linksLocalizeForAddress != nil && len(linksLocalizeConfigContent) > 0 {
+			return errors.New("--links.localize.address-regex and --links.localize.config are mutually exclusive")
+		}
 		if *linksLocalizeForAddress != nil {
 			linkTr = append(linkTr, linktransformer.NewLocalizer(logger, *linksLocalizeForAddress, anchorDir))
 		}
+		if len(linksLocalizeConfigContent) > 0 {
+			l, err := linktransformer.NewLocalizerFromConfig(logger, linksLocalizeConfigContent, anchorDir)
+			if err != nil {
+				return err
+			}
+			linkTr = append(linkTr, l)
+		}
+		if *linksGlobalizeBase != "" {
+			// Last in chain, so local links are validated before.
+			g, err := linktransformer.NewGlobalizer(logger, *linksGlobalizeBase, anchorDir, *linksGlobalizeExtensions)
+			if err != nil {
+				return err
+			}
+			linkTr = append(linkTr, g)
+		}
 
 		if len(linkTr) > 0 {
 			opts = append(opts, mdformatter.WithLinkTransformer(linktransformer.NewChain(linkTr...)))
 		}
 
+		if *linksFixRedirects {
+			// Redirects are known only after links are checked, so check them all before rewriting. Validation errors
+			// are reported again in the next pass.
+			// Metrics are registered only for the final pass.
+			if _, err := mdformatter.IsFormatted(ctx, logger, *files, opts...); err != nil {
+				level.Debug(logger).Log("msg", "links check before fixing redirects failed", "err", err)
+			}
+			warnings.Reset()
+		}
+
 		opts = append(opts, mdformatter.WithMetrics(reg))
 
 		if *checkOnly {
@@ -299,7 +408,11 @@ This directive runs executable with arguments and put its stderr and stdout outp
 			return fmt.Errorf("files not formatted: %v", diffOut)
 
 		}
-		if err := mdformatter.Format(ctx, logger, *files, opts...); err != nil {
+		if *linksGlobalizeOutput != "" {
+			if err := mdformatter.FormatInto(ctx, logger, (*files)[0], *linksGlobalizeOutput, opts...); err != nil {
+				return err
+			}
+		} else if err := mdformatter.Format(ctx, logger, *files, opts...); err != nil {
 			return err
 		}
 		if reg != nil {
@@ -345,3 +458,387 @@ func registerTransform(_ context.Context, app *extkingpin.App) {
 		return transform.Dir(ctx, logger, validateConfig)
 	})
 }
+
+// configNames are names of mdox YAML configurations, by flag they are passed in.
+var configNames = []string{"links.validate", "links.rewrite", "links.localize", "transform"}
+
+// configObject returns configuration struct with defaults for given name from configNames.
+func configObject(name string) interface{} {
+	switch name {
+	case "links.validate":
+		return linktransformer.Config{Cache: cache.NewConfig()}
+	case "links.rewrite":
+		return linktransformer.RewriteConfig{}
+	case "links.localize":
+		return linktransformer.LocalizeConfig{}
+	case "transform":
+		return transform.Config{}
+	}
+	return nil
+}
+
+func registerSchema(_ context.Context, app *extkingpin.App) {
+	cmd := app.Command("schema", "Generates JSON Schema (draft 2020-12) of mdox YAML configuration, so configuration files can be validated e.g. by editors. Example: mdox schema links.validate")
+	config := cmd.Arg("config", "Configuration to generate schema for.").Required().Enum(configNames...)
+	output := cmd.Flag("output", "Path to the file schema is written into. If empty, schema is printed to stdout.").String()
+	cmd.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		obj := configObject(*config)
+		if *output == "" {
+			return yamlgen.GenerateJSONSchema(obj, os.Stdout)
+		}
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, f.Close, "close")
+		return yamlgen.GenerateJSONSchema(obj, f)
+	})
+}
+
+func registerCfgTable(_ context.Context, app *extkingpin.App) {
+	cmd := app.Command("cfgtable", "Generates markdown reference of mdox YAML configuration, with table of keys, types, defaults and descriptions per struct, and prints it to stdout. "+
+		"Useful in generated sections e.g. <!-- mdox-gen-exec=\"mdox cfgtable links.validate\" -->")
+	config := cmd.Arg("config", "Configuration to generate reference for.").Required().Enum(configNames...)
+	headingLevel := cmd.Flag("heading-level", "Markdown heading level of each struct section.").Default("3").Int()
+	cmd.Run(func(ctx context.Context, logger log.Logger) error {
+		return yamlgen.GenerateTable(configObject(*config), os.Stdout, yamlgen.WithHeadingLevel(*headingLevel))
+	})
+}
+
+func registerAPIDoc(_ context.Context, app *extkingpin.App) {
+	cmd := app.Command("apidoc", "Generates markdown API reference of Go package (exported constants, variables, functions, types, methods and examples) and prints it to stdout. "+
+		"Useful in generated sections e.g. <!-- mdox-gen-exec=\"mdox apidoc ./pkg/foo\" -->")
+	dir := cmd.Arg("dir", "Directory of Go package to document.").Required().ExistingDir()
+	headingLevel := cmd.Flag("heading-level", "Markdown heading level of the package title. Declarations use deeper levels.").Default("2").Int()
+	cmd.Run(func(ctx context.Context, logger log.Logger) error {
+		return apidoc.Generate(*dir, os.Stdout, apidoc.WithHeadingLevel(*headingLevel))
+	})
+}
+
+func registerCache(_ context.Context, app *extkingpin.App, cachePath *string) {
+	cmd := app.Command("cache", "Inspects and manages cache of link checks. Cache type is taken from --links.validate.config.")
+	linksValidateConfig := extflag.RegisterPathOrContent(cmd, "links.validate.config", "YAML file with cache configuration, with spec defined in github.com/bwplotka/mdox/pkg/linktransformer.ValidatorConfig", extflag.WithEnvSubstitution())
+
+	// openCache opens storage configured in links validate config.
+	openCache := func() (cache.Storage, error) {
+		content, err := linksValidateConfig.Content()
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := linktransformer.ParseConfig(content)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.Cache.IsSet() {
+			return nil, errors.New("cache is not enabled, set cache type in --links.validate.config")
+		}
+		storage := cache.NewStorage(*cachePath, false)
+		if err := storage.Init(cfg.Cache); err != nil {
+			return nil, err
+		}
+		return storage, nil
+	}
+
+	ls := cmd.Command("ls", "Lists cached URLs with their age and status.")
+	ls.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		entries, err := storage.Entries()
+		if err != nil {
+			return err
+		}
+		urls := make([]string, 0, len(entries))
+		for u := range entries {
+			urls = append(urls, u)
+		}
+		sort.Strings(urls)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "URL\tAGE\tSTATUS\tFRESH")
+		for _, u := range urls {
+			e, fresh, err := storage.Lookup(u)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", u, time.Since(e.Timestamp).Round(time.Second), cacheStatus(e.Result), fresh)
+		}
+		return w.Flush()
+	})
+
+	prune := cmd.Command("prune", "Deletes cached entries older than given duration.")
+	olderThan := prune.Flag("older-than", "Entries older than this duration are deleted e.g. 240h.").Required().Duration()
+	prune.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		deleted, err := cache.Prune(storage, *olderThan)
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "pruned cache", "deleted", deleted)
+		return nil
+	})
+
+	rm := cmd.Command("rm", "Deletes cached entries with URL matching given regexp.")
+	urlRegex := rm.Arg("url-regex", "Regexp matching URLs to delete.").Required().Regexp()
+	rm.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		deleted, err := cache.Remove(storage, *urlRegex)
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "removed from cache", "deleted", deleted)
+		return nil
+	})
+
+	export := cmd.Command("export", "Exports cache into portable JSON file, so warm cache can be shared e.g. between CI runners.")
+	exportOutput := export.Flag("output", "Path to the file cache is exported into. If empty, it's printed to stdout.").String()
+	export.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		if *exportOutput == "" {
+			return cache.Export(storage, os.Stdout)
+		}
+		f, err := os.Create(*exportOutput)
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, f.Close, "close")
+		return cache.Export(storage, f)
+	})
+
+	imp := cmd.Command("import", "Imports cache exported by 'mdox cache export'. Entries already cached more recently are kept.")
+	importFile := imp.Arg("file", "Path to the exported cache file.").Required().ExistingFile()
+	imp.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		f, err := os.Open(*importFile)
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, f.Close, "close")
+
+		imported, err := cache.Import(storage, f)
+		if err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "imported cache", "imported", imported)
+		return nil
+	})
+
+	stats := cmd.Command("stats", "Prints cache statistics.")
+	stats.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		storage, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer errcapture.Do(&err, storage.Close, "close cache")
+
+		entries, err := storage.Entries()
+		if err != nil {
+			return err
+		}
+		var (
+			fresh    int
+			oldest   time.Time
+			outcomes = map[string]int{}
+		)
+		for u, e := range entries {
+			_, f, err := storage.Lookup(u)
+			if err != nil {
+				return err
+			}
+			if f {
+				fresh++
+			}
+			if oldest.IsZero() || e.Timestamp.Before(oldest) {
+				oldest = e.Timestamp
+			}
+			outcomes[cacheStatus(e.Result)]++
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "entries\t%v\n", len(entries))
+		fmt.Fprintf(w, "fresh\t%v\n", fresh)
+		fmt.Fprintf(w, "expired\t%v\n", len(entries)-fresh)
+		if !oldest.IsZero() {
+			fmt.Fprintf(w, "oldest\t%v\n", time.Since(oldest).Round(time.Second))
+		}
+		statuses := make([]string, 0, len(outcomes))
+		for s := range outcomes {
+			statuses = append(statuses, s)
+		}
+		sort.Strings(statuses)
+		for _, s := range statuses {
+			fmt.Fprintf(w, "status %v\t%v\n", s, outcomes[s])
+		}
+		return w.Flush()
+	})
+}
+
+// cacheStatus returns human readable status of cached result e.g. "200" or "404 (status)".
+func cacheStatus(r cache.Result) string {
+	if r.ErrorClass == cache.ErrorClassNone {
+		return strconv.Itoa(r.StatusCode)
+	}
+	if r.StatusCode == 0 {
+		return string(r.ErrorClass)
+	}
+	return fmt.Sprintf("%v (%v)", r.StatusCode, r.ErrorClass)
+}
+
+func registerLinks(_ context.Context, app *extkingpin.App, cachePath *string) {
+	cmd := app.Command("links", "Analyzes links between local files.")
+
+	orphans := cmd.Command("orphans", "Lists files which are not reachable by links from root files, so they are invisible on GitHub and websites. "+
+		"Markdown files are parsed for links, other files (e.g. images) are only checked for being linked. Example: mdox links orphans --root README.md $(find docs -name '*.md' -o -name '*.png')")
+	roots := orphans.Flag("root", "Entry point markdown file e.g. README.md. Can be specified multiple times.").Required().ExistingFiles()
+	anchorDir := orphans.Flag("anchor-dir", "Anchor directory for links absolute to it. Orphans are printed relative to it. PWD is used if flag is not specified.").ExistingDir()
+	baseline := orphans.Flag("baseline", "File with known orphans, one path relative to anchor dir per line, e.g. output of previous run. If specified, orphans not listed in it fail the command.").ExistingFile()
+	files := orphans.Arg("files", "Markdown and other files to check.").Required().ExistingFiles()
+	orphans.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		for _, fs := range []*[]string{roots, files} {
+			for i := range *fs {
+				(*fs)[i], err = filepath.Abs((*fs)[i])
+				if err != nil {
+					return err
+				}
+			}
+		}
+		anchorDir, err := validateAnchorDir(*anchorDir, append(append([]string(nil), *roots...), *files...))
+		if err != nil {
+			return err
+		}
+
+		mdFiles := append([]string(nil), *roots...)
+		for _, f := range *files {
+			if filepath.Ext(f) == ".md" {
+				mdFiles = append(mdFiles, f)
+			}
+		}
+		g := linktransformer.NewLinkGraph(anchorDir)
+		// Files are only checked, not formatted, to collect links.
+		if _, err := mdformatter.IsFormatted(ctx, logger, mdFiles, mdformatter.WithLinkTransformer(g)); err != nil {
+			return err
+		}
+
+		known := map[string]struct{}{}
+		if *baseline != "" {
+			b, err := os.ReadFile(*baseline)
+			if err != nil {
+				return err
+			}
+			for _, l := range strings.Split(string(b), "\n") {
+				if l = strings.TrimSpace(l); l != "" {
+					known[l] = struct{}{}
+				}
+			}
+		}
+
+		var newOrphans []string
+		for _, o := range g.Orphans(*roots, *files) {
+			rel, err := filepath.Rel(anchorDir, o)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			fmt.Println(rel)
+			if _, ok := known[rel]; !ok {
+				newOrphans = append(newOrphans, rel)
+			}
+		}
+		if *baseline != "" && len(newOrphans) > 0 {
+			return fmt.Errorf("%v orphaned files not in baseline %v: %v", len(newOrphans), *baseline, strings.Join(newOrphans, ", "))
+		}
+		return nil
+	})
+
+	export := cmd.Command("export", "Prints all links of given markdown files with their source file, line, text, destination, type (internal or external) and optionally validation status, without modifying files. "+
+		"Example: mdox links export --format=csv --links.validate $(find . -name '*.md')")
+	format := export.Flag("format", "Output format. 'dot' prints Graphviz graph of links between files, with external links pointing to their domain.").Default("json").Enum("json", "csv", "dot")
+	exportAnchorDir := export.Flag("anchor-dir", "Anchor directory for links absolute to it. Files are printed relative to it. PWD is used if flag is not specified.").ExistingDir()
+	linksValidateEnabled := export.Flag("links.validate", "If true, links will be validated and their status printed. Export does not fail on failed links.").Bool()
+	linksValidateConfig := extflag.RegisterPathOrContent(export, "links.validate.config", "YAML file for skipping link check, with spec defined in github.com/bwplotka/mdox/pkg/linktransformer.ValidatorConfig", extflag.WithEnvSubstitution())
+	linksMode := export.Flag("links.mode", "Link validation mode, same as in 'mdox fmt'. Requires --links.validate.").
+		Default(string(linktransformer.ModeFull)).Enum(string(linktransformer.ModeFull), string(linktransformer.ModeLocalOnly), string(linktransformer.ModeCacheOnly))
+	exportFiles := export.Arg("files", "Markdown files to export links of.").Required().ExistingFiles()
+	export.Run(func(ctx context.Context, logger log.Logger) (err error) {
+		for i := range *exportFiles {
+			(*exportFiles)[i], err = filepath.Abs((*exportFiles)[i])
+			if err != nil {
+				return err
+			}
+		}
+		anchorDir, err := validateAnchorDir(*exportAnchorDir, *exportFiles)
+		if err != nil {
+			return err
+		}
+		if linktransformer.Mode(*linksMode) != linktransformer.ModeFull && !*linksValidateEnabled {
+			return errors.New("--links.mode requires --links.validate")
+		}
+
+		inv := linktransformer.NewLinkInventory(anchorDir)
+		linkTr := []mdformatter.LinkTransformer{inv}
+		if *linksValidateEnabled {
+			var validateConfigContent []byte
+			validateConfigContent, err = linksValidateConfig.Content()
+			if err != nil {
+				return err
+			}
+			storage := cache.NewStorage(*cachePath, false)
+			defer errcapture.Do(&err, storage.Close, "close cache")
+
+			var v mdformatter.LinkTransformer
+			v, err = linktransformer.NewValidator(ctx, logger, validateConfigContent, anchorDir, storage, nil,
+				linktransformer.WithMode(linktransformer.Mode(*linksMode)), linktransformer.WithResultFn(inv.SetResult))
+			if err != nil {
+				return err
+			}
+			linkTr = append(linkTr, resultsOnly{LinkTransformer: v})
+		}
+
+		// Files are only checked, not formatted.
+		if _, err := mdformatter.IsFormatted(ctx, logger, *exportFiles, mdformatter.WithLinkTransformer(linktransformer.NewChain(linkTr...))); err != nil {
+			return err
+		}
+
+		switch *format {
+		case "csv":
+			return inv.WriteCSV(os.Stdout)
+		case "dot":
+			return inv.WriteDOT(os.Stdout)
+		default:
+			return inv.WriteJSON(os.Stdout)
+		}
+	})
+}
+
+// resultsOnly is mdformatter.LinkTransformer which does not fail on failed links, as their results are reported otherwise.
+type resultsOnly struct {
+	mdformatter.LinkTransformer
+}
+
+func (r resultsOnly) Close(ctx mdformatter.SourceContext) error {
+	_ = r.LinkTransformer.Close(ctx)
+	return nil
+}
@@ -4,9 +4,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/bwplotka/mdox/pkg/mdformatter/mdgen"
 	"github.com/efficientgo/core/testutil"
 )
 
@@ -35,3 +41,24 @@ func TestValidateAnchorDir(t *testing.T) {
 	testutil.Ok(t, err)
 	testutil.Equals(t, "/root", anchorDir)
 }
+
+func TestCfgTable_GenSection(t *testing.T) {
+	// Build mdox, so generated section can execute it as documented in README.
+	binDir := t.TempDir()
+	out, err := exec.Command("go", "build", "-o", filepath.Join(binDir, "mdox"), ".").CombinedOutput()
+	testutil.Ok(t, err, string(out))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	file := filepath.Join(t.TempDir(), "config.md")
+	testutil.Ok(t, os.WriteFile(file, []byte("# Rewrites\n\n<!-- mdox-gen-exec=\"mdox cfgtable links.rewrite --heading-level=4\" -->\n...\n<!-- mdox-gen-end -->\n"), os.ModePerm))
+	in, err := os.Open(file)
+	testutil.Ok(t, err)
+	defer in.Close()
+
+	f := mdformatter.New(context.Background(), mdformatter.WithGenSectionTransformer(mdgen.NewGenSectionTransformer()))
+	buf := bytes.Buffer{}
+	testutil.Ok(t, f.Format(in, &buf))
+	exp, err := os.ReadFile("testdata/cfgtable_formatted.md")
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(exp), buf.String())
+}
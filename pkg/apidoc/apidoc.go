@@ -0,0 +1,387 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+// Package apidoc renders Go package API reference (similar to godoc) as markdown.
+package apidoc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/doc/comment"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bwplotka/mdox/pkg/gfm"
+)
+
+type options struct {
+	headingLevel int
+}
+
+// Option is a functional option for Generate.
+type Option func(*options)
+
+// WithHeadingLevel sets the markdown heading level of the package title. Sections use deeper levels. 2 by default.
+func WithHeadingLevel(level int) Option {
+	return func(o *options) {
+		o.headingLevel = level
+	}
+}
+
+// Generate parses Go package in given directory and writes markdown API reference of its exported constants, variables,
+// functions, types, methods and examples. Every declaration gets its own heading, so anchors are stable e.g.
+// `#func-new`, `#type-config` or `#func-config-isset` for `func (c *Config) IsSet() bool` method.
+func Generate(dir string, w io.Writer, opts ...Option) error {
+	o := options{headingLevel: 2}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.headingLevel < 1 || o.headingLevel > 4 {
+		return fmt.Errorf("heading level has to be between 1 and 4, got %v", o.headingLevel)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	importPath, err := importPath(dir)
+	if err != nil {
+		return fmt.Errorf("resolve import path of %v: %w", dir, err)
+	}
+
+	fset := token.NewFileSet()
+	// Test files are parsed too, as they hold examples. Only examples are taken from them.
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parse %v: %w", dir, err)
+	}
+
+	var files []*ast.File
+	var name string
+	for pkgName, p := range pkgs {
+		if !strings.HasSuffix(pkgName, "_test") {
+			if name != "" {
+				return fmt.Errorf("multiple packages found in %v: %v, %v", dir, name, pkgName)
+			}
+			name = pkgName
+		}
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("no Go package found in %v", dir)
+	}
+	// Deterministic order, map iteration is random.
+	sort.Slice(files, func(i, j int) bool {
+		return fset.Position(files[i].Package).Filename < fset.Position(files[j].Package).Filename
+	})
+
+	p, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return err
+	}
+
+	g := newGenerator(fset, p, files, o.headingLevel)
+	b := bytes.Buffer{}
+	g.write(&b)
+	_, err = w.Write(append(bytes.TrimRight(b.Bytes(), "\n"), '\n'))
+	return err
+}
+
+type generator struct {
+	fset  *token.FileSet
+	pkg   *doc.Package
+	files []*ast.File
+	level int
+	// anchors holds anchor of section documenting given top level declaration.
+	anchors map[string]string
+}
+
+func newGenerator(fset *token.FileSet, pkg *doc.Package, files []*ast.File, level int) *generator {
+	g := &generator{fset: fset, pkg: pkg, files: files, level: level, anchors: map[string]string{}}
+	values := func(vs []*doc.Value, anchor string) {
+		for _, v := range vs {
+			for _, n := range v.Names {
+				g.anchors[n] = anchor
+			}
+		}
+	}
+	funcs := func(fs []*doc.Func) {
+		for _, f := range fs {
+			g.anchors[f.Name] = gfm.HeaderID("func " + f.Name)
+		}
+	}
+	values(pkg.Consts, gfm.HeaderID("Constants"))
+	values(pkg.Vars, gfm.HeaderID("Variables"))
+	funcs(pkg.Funcs)
+	for _, t := range pkg.Types {
+		g.anchors[t.Name] = gfm.HeaderID("type " + t.Name)
+		values(t.Consts, gfm.HeaderID("type "+t.Name))
+		values(t.Vars, gfm.HeaderID("type "+t.Name))
+		funcs(t.Funcs)
+	}
+	return g
+}
+
+func (g *generator) heading(b *bytes.Buffer, level int, text string) {
+	_, _ = fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", g.level+level), text)
+}
+
+func (g *generator) write(b *bytes.Buffer) {
+	g.heading(b, 0, "package "+g.pkg.Name)
+	_, _ = fmt.Fprintf(b, "```go\nimport %q\n```\n\n", g.pkg.ImportPath)
+	g.doc(b, g.pkg.Doc)
+	g.examples(b, g.pkg.Examples, 1)
+
+	g.index(b)
+
+	if len(g.pkg.Consts) > 0 {
+		g.heading(b, 1, "Constants")
+		for _, v := range g.pkg.Consts {
+			g.decl(b, v.Decl)
+			g.doc(b, v.Doc)
+		}
+	}
+	if len(g.pkg.Vars) > 0 {
+		g.heading(b, 1, "Variables")
+		for _, v := range g.pkg.Vars {
+			g.decl(b, v.Decl)
+			g.doc(b, v.Doc)
+		}
+	}
+	for _, f := range g.pkg.Funcs {
+		g.fn(b, f, 1)
+	}
+	for _, t := range g.pkg.Types {
+		g.heading(b, 1, "type "+t.Name)
+		g.decl(b, t.Decl)
+		g.doc(b, t.Doc)
+		for _, v := range t.Consts {
+			g.decl(b, v.Decl)
+			g.doc(b, v.Doc)
+		}
+		for _, v := range t.Vars {
+			g.decl(b, v.Decl)
+			g.doc(b, v.Doc)
+		}
+		g.examples(b, t.Examples, 2)
+		for _, f := range t.Funcs {
+			g.fn(b, f, 2)
+		}
+		for _, f := range t.Methods {
+			g.fn(b, f, 2)
+		}
+	}
+}
+
+func (g *generator) index(b *bytes.Buffer) {
+	g.heading(b, 1, "Index")
+	if len(g.pkg.Consts) > 0 {
+		_, _ = fmt.Fprintf(b, "* [Constants](#%s)\n", gfm.HeaderID("Constants"))
+	}
+	if len(g.pkg.Vars) > 0 {
+		_, _ = fmt.Fprintf(b, "* [Variables](#%s)\n", gfm.HeaderID("Variables"))
+	}
+	for _, f := range g.pkg.Funcs {
+		_, _ = fmt.Fprintf(b, "* [%s](#%s)\n", g.signature(f.Decl), gfm.HeaderID(fnHeading(f)))
+	}
+	for _, t := range g.pkg.Types {
+		_, _ = fmt.Fprintf(b, "* [type %s](#%s)\n", t.Name, gfm.HeaderID("type "+t.Name))
+		for _, f := range t.Funcs {
+			_, _ = fmt.Fprintf(b, "  * [%s](#%s)\n", g.signature(f.Decl), gfm.HeaderID(fnHeading(f)))
+		}
+		for _, f := range t.Methods {
+			_, _ = fmt.Fprintf(b, "  * [%s](#%s)\n", g.signature(f.Decl), gfm.HeaderID(fnHeading(f)))
+		}
+	}
+	_, _ = b.WriteString("\n")
+}
+
+func (g *generator) fn(b *bytes.Buffer, f *doc.Func, level int) {
+	g.heading(b, level, fnHeading(f))
+	g.decl(b, f.Decl)
+	g.doc(b, f.Doc)
+	g.examples(b, f.Examples, level+1)
+}
+
+// fnHeading returns heading of function e.g. "func New" or method e.g. "func (*Config) IsSet".
+func fnHeading(f *doc.Func) string {
+	if f.Recv == "" {
+		return "func " + f.Name
+	}
+	recv := f.Recv
+	if f.Decl.Recv != nil && len(f.Decl.Recv.List) > 0 {
+		recv = recvType(f.Decl.Recv.List[0].Type)
+	}
+	return fmt.Sprintf("func (%s) %s", recv, f.Name)
+}
+
+// recvType returns receiver type name without type parameters e.g. "*List" for "*List[T]".
+func recvType(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.StarExpr:
+		return "*" + recvType(t.X)
+	case *ast.IndexExpr:
+		return recvType(t.X)
+	case *ast.IndexListExpr:
+		return recvType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// signature returns one line function declaration without body.
+func (g *generator) signature(decl *ast.FuncDecl) string {
+	d := *decl
+	d.Doc = nil
+	d.Body = nil
+	b := bytes.Buffer{}
+	_ = format.Node(&b, g.fset, &d)
+	// Escape characters with special meaning in link text.
+	return strings.NewReplacer("[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`).Replace(b.String())
+}
+
+// decl writes declaration as Go code block, including comments inside it (e.g. struct field docs).
+func (g *generator) decl(b *bytes.Buffer, decl ast.Decl) {
+	d := decl
+	switch t := decl.(type) {
+	case *ast.FuncDecl:
+		fd := *t
+		fd.Doc = nil
+		fd.Body = nil
+		d = &fd
+	case *ast.GenDecl:
+		gd := *t
+		gd.Doc = nil
+		d = &gd
+	}
+
+	var comments []*ast.CommentGroup
+	for _, f := range g.files {
+		for _, c := range f.Comments {
+			// Function bodies are not printed, so are comments in them.
+			if c.Pos() > d.Pos() && c.End() < d.End() {
+				comments = append(comments, c)
+			}
+		}
+	}
+
+	code := bytes.Buffer{}
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&code, g.fset, &printer.CommentedNode{Node: d, Comments: comments}); err != nil {
+		// Should not happen for parsed code, fallback to code without comments.
+		code.Reset()
+		_ = format.Node(&code, g.fset, d)
+	}
+	_, _ = fmt.Fprintf(b, "```go\n%s\n```\n\n", code.String())
+}
+
+// doc writes doc comment as markdown. Links to declarations of this package point to their anchors.
+func (g *generator) doc(b *bytes.Buffer, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	p := g.pkg.Printer()
+	p.HeadingLevel = g.level + 2
+	p.HeadingID = func(*comment.Heading) string { return "" }
+	p.DocLinkURL = func(link *comment.DocLink) string {
+		if link.ImportPath != "" && link.ImportPath != g.pkg.ImportPath {
+			return link.DefaultURL("https://pkg.go.dev")
+		}
+		if link.Recv != "" {
+			return "#" + gfm.HeaderID(fmt.Sprintf("func %s %s", link.Recv, link.Name))
+		}
+		return "#" + g.anchors[link.Name]
+	}
+	_, _ = b.Write(p.Markdown(g.pkg.Parser().Parse(text)))
+	_, _ = b.WriteString("\n")
+}
+
+func (g *generator) examples(b *bytes.Buffer, examples []*doc.Example, level int) {
+	for _, ex := range examples {
+		title := "Example"
+		if ex.Suffix != "" {
+			title += " (" + exampleSuffix(ex.Suffix) + ")"
+		}
+		g.heading(b, level, title)
+		g.doc(b, ex.Doc)
+
+		code := bytes.Buffer{}
+		if err := format.Node(&code, g.fset, ex.Code); err != nil {
+			continue
+		}
+		src := strings.TrimSpace(code.String())
+		if _, ok := ex.Code.(*ast.BlockStmt); ok {
+			// Render only body, similar to godoc.
+			src = unindent(strings.TrimSuffix(strings.TrimPrefix(src, "{"), "}"))
+		}
+		_, _ = fmt.Fprintf(b, "```go\n%s\n```\n\n", src)
+		if ex.Output != "" {
+			_, _ = fmt.Fprintf(b, "Output:\n\n```text\n%s\n```\n\n", strings.TrimSpace(ex.Output))
+		}
+	}
+}
+
+func exampleSuffix(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func unindent(s string) string {
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// importPath returns import path of package in given directory, based on the closest go.mod file.
+func importPath(dir string) (string, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		f, err := os.Open(filepath.Join(d, "go.mod"))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return "", err
+			}
+			if filepath.Dir(d) == d {
+				return "", errors.New("go.mod not found")
+			}
+			continue
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "module ") {
+				continue
+			}
+			_ = f.Close()
+			mod := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+			rel, err := filepath.Rel(d, dir)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return mod, nil
+			}
+			return mod + "/" + filepath.ToSlash(rel), nil
+		}
+		_ = f.Close()
+		return "", fmt.Errorf("no module directive in %v", filepath.Join(d, "go.mod"))
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package apidoc
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestGenerate(t *testing.T) {
+	exp, err := os.ReadFile("testdata/pkg.md")
+	testutil.Ok(t, err)
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, Generate("testdata/pkg", &b))
+	testutil.Equals(t, string(exp), b.String())
+
+	t.Run("output is formatted", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "pkg.md")
+		testutil.Ok(t, os.WriteFile(file, b.Bytes(), os.ModePerm))
+
+		f, err := os.Open(file)
+		testutil.Ok(t, err)
+		defer f.Close()
+
+		formatted := bytes.Buffer{}
+		testutil.Ok(t, mdformatter.New(context.Background()).Format(f, &formatted))
+		testutil.Equals(t, b.String(), formatted.String())
+	})
+
+	testutil.NotOk(t, Generate("testdata", &b))
+	testutil.NotOk(t, Generate("testdata/pkg", &b, WithHeadingLevel(5)))
+}
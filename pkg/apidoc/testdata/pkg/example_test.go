@@ -0,0 +1,16 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package pkg_test
+
+import (
+	"fmt"
+
+	"github.com/bwplotka/mdox/pkg/apidoc/testdata/pkg"
+)
+
+func ExampleNew() {
+	c := pkg.New()
+	fmt.Println(c.Name)
+	// Output: default
+}
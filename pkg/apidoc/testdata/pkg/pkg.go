@@ -0,0 +1,46 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+// Package pkg is a test package for API reference generation.
+//
+// Start with [New] and then use [Config.IsSet].
+package pkg
+
+import "errors"
+
+// DefaultName is the default name.
+const DefaultName = "default"
+
+// ErrNotFound is returned when nothing is found.
+var ErrNotFound = errors.New("not found")
+
+// Config holds options.
+type Config struct {
+	// Name of the thing.
+	Name string
+	// Tags attached to the thing.
+	Tags []string
+
+	hidden int
+}
+
+// New returns new Config with [DefaultName].
+func New() *Config {
+	// Internal comment, should not be rendered.
+	return &Config{Name: DefaultName}
+}
+
+// IsSet returns true if name is set.
+func (c *Config) IsSet() bool {
+	return c.Name != ""
+}
+
+// Parse parses given string. See also [errors.New].
+func Parse(s string) (Config, error) {
+	if s == "" {
+		return Config{}, ErrNotFound
+	}
+	return Config{Name: s}, nil
+}
+
+func unexported() {}
@@ -7,7 +7,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"math/rand"
 	"sync"
 	"time"
 
@@ -18,7 +17,7 @@ const (
 	driverName = "sqlite3"
 )
 
-// Sqlite3Storage implements a SQLite3 caching backend for Colly.
+// SQLite3Storage implements a SQLite3 caching backend for Colly.
 type SQLite3Storage struct {
 	// SQLite filename.
 	Filename string
@@ -32,12 +31,12 @@ type SQLite3Storage struct {
 	dbHandle *sql.DB
 	// Mutex used for clearing cache database.
 	mu sync.RWMutex
-	// Rand for jitter.
-	r *rand.Rand
+
+	expiry *expiry
 }
 
 // Init initializes cache database.
-func (s *SQLite3Storage) Init(validity time.Duration, jitter time.Duration) error {
+func (s *SQLite3Storage) Init(cfg Config) error {
 	// Check if db exists.
 	if s.dbHandle != nil {
 		return errors.New("dbHandle should not be pre-populated")
@@ -75,14 +74,57 @@ func (s *SQLite3Storage) Init(validity time.Duration, jitter time.Duration) erro
 	if _, err = statement.Exec(); err != nil {
 		return err
 	}
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("migrate cache database: %w", err)
+	}
 
-	s.Validity = validity
-	s.Jitter = jitter
-	s.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	s.Validity = cfg.Validity
+	s.Jitter = cfg.Jitter
+	s.expiry = newExpiry(cfg)
 
 	return nil
 }
 
+// migrate adds columns missing in cache databases created by older versions. All entries cached before were successes.
+func (s *SQLite3Storage) migrate() error {
+	rows, err := s.dbHandle.Query("PRAGMA table_info(visited)")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := map[string]struct{}{}
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		columns[name] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range []struct{ name, def string }{
+		{name: "status", def: "INTEGER NOT NULL DEFAULT 200"},
+		{name: "error_class", def: "TEXT NOT NULL DEFAULT ''"},
+		{name: "etag", def: "TEXT NOT NULL DEFAULT ''"},
+		{name: "last_modified", def: "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if _, ok := columns[c.name]; ok {
+			continue
+		}
+		if _, err := s.dbHandle.Exec(fmt.Sprintf("ALTER TABLE visited ADD COLUMN %s %s", c.name, c.def)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Clear removes all entries from cache.
 func (s *SQLite3Storage) Clear() error {
 	s.mu.Lock()
@@ -104,49 +146,108 @@ func (s *SQLite3Storage) Close() error {
 	return s.dbHandle.Close()
 }
 
-// CacheURL inserts new URL into cache database.
+// CacheURL inserts new successfully visited URL into cache database.
 func (s *SQLite3Storage) CacheURL(URL string) error {
+	return s.CacheResult(URL, Result{StatusCode: 200})
+}
+
+// CacheResult inserts result of URL check into cache database. Failures are stored only if there is validity
+// configured for them.
+func (s *SQLite3Storage) CacheResult(URL string, r Result) error {
+	if !s.expiry.cacheable(r) {
+		return nil
+	}
+
 	// If particular URL is already inserted, then delete.
-	// CacheURL method will only be called if validity expires for a URL or in case of a new URL.
+	// CacheResult method will only be called if validity expires for a URL or in case of a new URL.
 	if err := s.DeleteURL(URL); err != nil {
 		return err
 	}
 
 	// Insert with current UTC Unix timestamp.
-	statement, err := s.dbHandle.Prepare("INSERT INTO visited (url, visited, timestamp) VALUES (?, 1, strftime('%s', 'now'))")
+	statement, err := s.dbHandle.Prepare("INSERT INTO visited (url, visited, timestamp, status, error_class, etag, last_modified) VALUES (?, 1, strftime('%s', 'now'), ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
-	if _, err = statement.Exec(URL); err != nil {
+	if _, err = statement.Exec(URL, r.StatusCode, string(r.ErrorClass), r.ETag, r.LastModified); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// IsCached checks if URL has already been visited.
-func (s *SQLite3Storage) IsCached(URL string) (bool, error) {
-	var timestamp time.Time
-	statement, err := s.dbHandle.Prepare("SELECT timestamp FROM visited where url = ?")
+// Put inserts entry into cache database as is, including its timestamp.
+func (s *SQLite3Storage) Put(URL string, e Entry) error {
+	if err := s.DeleteURL(URL); err != nil {
+		return err
+	}
+
+	statement, err := s.dbHandle.Prepare("INSERT INTO visited (url, visited, timestamp, status, error_class, etag, last_modified) VALUES (?, 1, ?, ?, ?, ?, ?)")
 	if err != nil {
+		return err
+	}
+	if _, err = statement.Exec(URL, e.Timestamp.Unix(), e.StatusCode, string(e.ErrorClass), e.ETag, e.LastModified); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Entries returns all entries from cache database by URL.
+func (s *SQLite3Storage) Entries() (map[string]Entry, error) {
+	rows, err := s.dbHandle.Query("SELECT url, timestamp, status, error_class, etag, last_modified FROM visited")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := map[string]Entry{}
+	for rows.Next() {
+		var (
+			url, errorClass string
+			e               Entry
+		)
+		if err := rows.Scan(&url, &e.Timestamp, &e.StatusCode, &errorClass, &e.ETag, &e.LastModified); err != nil {
+			return nil, err
+		}
+		e.ErrorClass = ErrorClass(errorClass)
+		entries[url] = e
+	}
+	return entries, rows.Err()
+}
+
+// IsCached checks if URL has already been successfully visited.
+func (s *SQLite3Storage) IsCached(URL string) (bool, error) {
+	e, fresh, err := s.Lookup(URL)
+	if err != nil || e == nil {
 		return false, err
 	}
+	return fresh && e.ErrorClass == ErrorClassNone, nil
+}
+
+// Lookup returns cached entry for URL (nil if there is none) and whether it's within its validity threshold. Expired
+// entries with ETag or LastModified can be revalidated with conditional request.
+func (s *SQLite3Storage) Lookup(URL string) (_ *Entry, fresh bool, _ error) {
+	var (
+		timestamp  time.Time
+		errorClass string
+		e          Entry
+	)
+	statement, err := s.dbHandle.Prepare("SELECT timestamp, status, error_class, etag, last_modified FROM visited where url = ?")
+	if err != nil {
+		return nil, false, err
+	}
 	row := statement.QueryRow(URL)
-	if err = row.Scan(&timestamp); err != nil {
+	if err = row.Scan(&timestamp, &e.StatusCode, &errorClass, &e.ETag, &e.LastModified); err != nil {
 		// If ErrNoRows then it means URL is new, so need to call Visited.
 		if err == sql.ErrNoRows {
-			return false, nil
+			return nil, false, nil
 		}
-		return false, err
-	}
-
-	// Check if URL is within validity threshold with jitter (0 is no jitter provided or rand(0->jitter)).
-	jitterValue := time.Duration(0)
-	if s.Jitter != time.Duration(0) {
-		jitterValue = time.Duration(s.r.Intn(int(s.Jitter)))
+		return nil, false, err
 	}
+	e.Timestamp = timestamp
+	e.ErrorClass = ErrorClass(errorClass)
 
-	return (!timestamp.IsZero() && time.Since(timestamp)+jitterValue <= s.Validity), nil
+	return &e, s.expiry.isFresh(e), nil
 }
 
 // DeleteURL deletes a URL from cache database.
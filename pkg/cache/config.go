@@ -4,6 +4,7 @@
 package cache
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,6 +19,8 @@ const (
 	cacheTypeEmpty       = cacheType("")
 	cacheTypeNone        = cacheType("none")
 	cacheTypeSQLite      = cacheType("sqlite")
+	cacheTypeFile        = cacheType("file")
+	cacheTypeMemory      = cacheType("memory")
 )
 
 // Config holds the cache configuration.
@@ -28,6 +31,8 @@ type Config struct {
 	Validity time.Duration
 	// Jitter is the jitter to apply when considering a cached entry valid or not.
 	Jitter time.Duration
+	// FailureValidity is the duration for which the cached failure is valid, by status code or ErrorClass.
+	FailureValidity map[string]time.Duration
 
 	cacheParser *configParser
 }
@@ -60,7 +65,7 @@ func (c *Config) UnmarshalYAML(value *yaml.Node) error {
 // into the configuration.
 func (c *Config) load() error {
 	switch c.cacheParser.Type {
-	case cacheTypeSQLite:
+	case cacheTypeSQLite, cacheTypeFile, cacheTypeMemory:
 		if c.cacheParser.Validity != "" {
 			var err error
 			c.Validity, err = time.ParseDuration(c.cacheParser.Validity)
@@ -76,6 +81,20 @@ func (c *Config) load() error {
 				return errors.Wrap(err, "parsing cache jitter duration")
 			}
 		}
+
+		if len(c.cacheParser.FailureValidity) > 0 {
+			c.FailureValidity = make(map[string]time.Duration, len(c.cacheParser.FailureValidity))
+		}
+		for outcome, validity := range c.cacheParser.FailureValidity {
+			if !isValidOutcome(outcome) {
+				return errors.Errorf("unsupported failure outcome %q, expected status code or one of %v, %v, %v", outcome, ErrorClassStatus, ErrorClassTimeout, ErrorClassNetwork)
+			}
+			var err error
+			c.FailureValidity[outcome], err = time.ParseDuration(validity)
+			if err != nil {
+				return errors.Wrapf(err, "parsing cache failure validity duration for %v", outcome)
+			}
+		}
 	case cacheTypeNone, cacheTypeEmpty:
 	default:
 		return errors.New("unsupported cache type")
@@ -84,13 +103,34 @@ func (c *Config) load() error {
 	return nil
 }
 
+// YAMLRepresentation returns the parsed form of configuration, so schema can be generated for it.
+func (c Config) YAMLRepresentation() interface{} {
+	return newConfigParser()
+}
+
 // configParser represents a cache configuration that can be parsed.
 // These fields are not embed in a unified Config struct to avoid accidental
 // usage of the duration fields (i.e. Validity and Jitter) as strings.
 type configParser struct {
-	Type     cacheType `yaml:"type"`
-	Validity string    `yaml:"validity"`
-	Jitter   string    `yaml:"jitter"`
+	// Type of the cache: `sqlite`, `file` (pure Go, JSON file) or `memory` (not persisted). Empty or `none` disables caching.
+	Type cacheType `yaml:"type" jsonschema:"enum=sqlite,enum=file,enum=memory,enum=none"`
+	// Validity is the duration for which visited link is considered valid e.g. "120h".
+	Validity string `yaml:"validity"`
+	// Jitter is the maximum random duration added when checking validity of cached entries.
+	Jitter string `yaml:"jitter"`
+	// FailureValidity is the duration for which failed link is considered failed, by status code (e.g. "404") or error
+	// class ("status", "timeout" or "network") e.g. {"404": "24h", "timeout": "1h"}. Status code takes precedence.
+	// Failures without validity are not cached.
+	FailureValidity map[string]string `yaml:"failureValidity"`
+}
+
+func isValidOutcome(outcome string) bool {
+	switch ErrorClass(outcome) {
+	case ErrorClassStatus, ErrorClassTimeout, ErrorClassNetwork:
+		return true
+	}
+	code, err := strconv.Atoi(outcome)
+	return err == nil && code >= 100 && code <= 599
 }
 
 // newConfigParser is the constructor for ConfigParser.
@@ -0,0 +1,146 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const fileStorageVersion = 1
+
+// FileStorage implements pure Go caching backend, persisting entries in JSON file. Entries are kept in memory and
+// written to file on Close.
+type FileStorage struct {
+	// JSON filename.
+	Filename string
+	// Clear cache at start if true.
+	ClearCache bool
+
+	MemoryStorage
+}
+
+type fileContent struct {
+	Version int                  `json:"version"`
+	Entries map[string]fileEntry `json:"entries"`
+}
+
+type fileEntry struct {
+	StatusCode   int        `json:"status"`
+	ErrorClass   ErrorClass `json:"errorClass,omitempty"`
+	ETag         string     `json:"etag,omitempty"`
+	LastModified string     `json:"lastModified,omitempty"`
+	Timestamp    time.Time  `json:"timestamp"`
+}
+
+// Init initializes cache, loading entries from file, if it exists.
+func (s *FileStorage) Init(cfg Config) error {
+	if err := s.MemoryStorage.Init(cfg); err != nil {
+		return err
+	}
+	if s.ClearCache {
+		return s.Clear()
+	}
+
+	b, err := os.ReadFile(s.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read cache file: %w", err)
+	}
+
+	entries, err := decodeEntries(b)
+	if err != nil {
+		if errors.Is(err, errUnsupportedVersion) {
+			// Unknown format, start from scratch.
+			return nil
+		}
+		return fmt.Errorf("parse cache file %v, remove it or clear cache: %w", s.Filename, err)
+	}
+	s.entries = entries
+	return nil
+}
+
+// Clear removes all entries from cache, including cache file.
+func (s *FileStorage) Clear() error {
+	if err := s.MemoryStorage.Clear(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Close writes all entries to cache file.
+func (s *FileStorage) Close() error {
+	entries, err := s.Entries()
+	if err != nil {
+		return err
+	}
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+	// Write to temporary file first, so cache is not corrupted on failure.
+	tmp, err := os.CreateTemp(filepath.Dir(s.Filename), filepath.Base(s.Filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Filename)
+}
+
+var errUnsupportedVersion = errors.New("unsupported cache file version")
+
+// encodeEntries encodes entries in portable JSON format, used by file backend and cache export.
+func encodeEntries(entries map[string]Entry) ([]byte, error) {
+	c := fileContent{Version: fileStorageVersion, Entries: make(map[string]fileEntry, len(entries))}
+	for u, e := range entries {
+		c.Entries[u] = fileEntry{
+			StatusCode:   e.StatusCode,
+			ErrorClass:   e.ErrorClass,
+			ETag:         e.ETag,
+			LastModified: e.LastModified,
+			Timestamp:    e.Timestamp,
+		}
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// decodeEntries decodes entries encoded by encodeEntries.
+func decodeEntries(b []byte) (map[string]Entry, error) {
+	c := fileContent{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if c.Version != fileStorageVersion {
+		return nil, fmt.Errorf("%w %v, expected %v", errUnsupportedVersion, c.Version, fileStorageVersion)
+	}
+	entries := make(map[string]Entry, len(c.Entries))
+	for u, e := range c.Entries {
+		entries[u] = Entry{
+			Result:    Result{StatusCode: e.StatusCode, ErrorClass: e.ErrorClass, ETag: e.ETag, LastModified: e.LastModified},
+			Timestamp: e.Timestamp,
+		}
+	}
+	return entries, nil
+}
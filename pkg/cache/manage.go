@@ -0,0 +1,83 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Export writes all entries from storage into w in portable JSON format, so cache can be shared e.g. between CI runners.
+func Export(s Storage, w io.Writer) error {
+	entries, err := s.Entries()
+	if err != nil {
+		return err
+	}
+	b, err := encodeEntries(entries)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Import reads entries exported by Export and puts them into storage, preserving their timestamps. Entries newer in
+// storage are not overwritten. It returns number of imported entries.
+func Import(s Storage, r io.Reader) (int, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := decodeEntries(b)
+	if err != nil {
+		return 0, fmt.Errorf("parse exported cache: %w", err)
+	}
+	existing, err := s.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for u, e := range entries {
+		if ex, ok := existing[u]; ok && !ex.Timestamp.Before(e.Timestamp) {
+			continue
+		}
+		if err := s.Put(u, e); err != nil {
+			return imported, fmt.Errorf("import %v: %w", u, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// Prune deletes entries older than given duration. It returns number of deleted entries.
+func Prune(s Storage, olderThan time.Duration) (int, error) {
+	return deleteMatching(s, func(_ string, e Entry) bool { return time.Since(e.Timestamp) > olderThan })
+}
+
+// Remove deletes entries with URL matching given regexp. It returns number of deleted entries.
+func Remove(s Storage, re *regexp.Regexp) (int, error) {
+	return deleteMatching(s, func(u string, _ Entry) bool { return re.MatchString(u) })
+}
+
+func deleteMatching(s Storage, match func(URL string, e Entry) bool) (int, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for u, e := range entries {
+		if !match(u, e) {
+			continue
+		}
+		if err := s.DeleteURL(u); err != nil {
+			return deleted, fmt.Errorf("delete %v: %w", u, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
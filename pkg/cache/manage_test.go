@@ -0,0 +1,68 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestManage(t *testing.T) {
+	cfg := parseConfig(t, "type: memory\n")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s := &MemoryStorage{}
+	testutil.Ok(t, s.Init(cfg))
+	testutil.Ok(t, s.Put("https://example.com/new", Entry{Result: Result{StatusCode: 200, ETag: `"1"`}, Timestamp: now}))
+	testutil.Ok(t, s.Put("https://example.com/old", Entry{Result: Result{StatusCode: 200}, Timestamp: now.Add(-48 * time.Hour)}))
+	testutil.Ok(t, s.Put("https://other.com/404", Entry{Result: Result{StatusCode: 404, ErrorClass: ErrorClassStatus}, Timestamp: now}))
+
+	t.Run("export and import", func(t *testing.T) {
+		b := bytes.Buffer{}
+		testutil.Ok(t, Export(s, &b))
+
+		imported := &MemoryStorage{}
+		testutil.Ok(t, imported.Init(cfg))
+		// More recent entry is kept.
+		testutil.Ok(t, imported.Put("https://example.com/old", Entry{Result: Result{StatusCode: 200}, Timestamp: now}))
+
+		n, err := Import(imported, &b)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 2, n)
+
+		exp, err := s.Entries()
+		testutil.Ok(t, err)
+		exp["https://example.com/old"] = Entry{Result: Result{StatusCode: 200}, Timestamp: now}
+		got, err := imported.Entries()
+		testutil.Ok(t, err)
+		testutil.Equals(t, len(exp), len(got))
+		for u, e := range exp {
+			testutil.Assert(t, e.Timestamp.Equal(got[u].Timestamp), u)
+			testutil.Equals(t, e.Result, got[u].Result)
+		}
+
+		_, err = Import(imported, bytes.NewBufferString(`{"version": 2}`))
+		testutil.NotOk(t, err)
+	})
+	t.Run("remove", func(t *testing.T) {
+		n, err := Remove(s, regexp.MustCompile(`other\.com`))
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, n)
+	})
+	t.Run("prune", func(t *testing.T) {
+		n, err := Prune(s, 24*time.Hour)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, n)
+
+		entries, err := s.Entries()
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, len(entries))
+		_, ok := entries["https://example.com/new"]
+		testutil.Assert(t, ok)
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// MemoryStorage implements in-memory caching backend. Cache is lost on exit, so it's useful mostly for library and
+// test usage.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+
+	expiry *expiry
+}
+
+// Init initializes in-memory cache.
+func (s *MemoryStorage) Init(cfg Config) error {
+	if s.expiry != nil {
+		return errors.New("storage already initialized")
+	}
+	s.entries = map[string]Entry{}
+	s.expiry = newExpiry(cfg)
+	return nil
+}
+
+// CacheResult stores result of URL check.
+func (s *MemoryStorage) CacheResult(URL string, r Result) error {
+	if !s.expiry.cacheable(r) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[URL] = Entry{Result: r, Timestamp: time.Now()}
+	return nil
+}
+
+// Lookup returns cached entry for URL, if any.
+func (s *MemoryStorage) Lookup(URL string) (_ *Entry, fresh bool, _ error) {
+	s.mu.RLock()
+	e, ok := s.entries[URL]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	return &e, s.expiry.isFresh(e), nil
+}
+
+// Put stores entry as is, including its timestamp.
+func (s *MemoryStorage) Put(URL string, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[URL] = e
+	return nil
+}
+
+// Entries returns copy of all entries by URL.
+func (s *MemoryStorage) Entries() (map[string]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make(map[string]Entry, len(s.entries))
+	for u, e := range s.entries {
+		entries[u] = e
+	}
+	return entries, nil
+}
+
+// DeleteURL deletes URL from cache.
+func (s *MemoryStorage) DeleteURL(URL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, URL)
+	return nil
+}
+
+// Clear removes all entries from cache.
+func (s *MemoryStorage) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[string]Entry{}
+	return nil
+}
+
+// Close is no-op for in-memory cache.
+func (s *MemoryStorage) Close() error { return nil }
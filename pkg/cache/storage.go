@@ -0,0 +1,152 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Storage is a cache of link check results.
+type Storage interface {
+	// Init initializes storage with given configuration. It has to be called before any other method.
+	Init(cfg Config) error
+	// CacheResult stores result of URL check. Failures are stored only if there is validity configured for them.
+	CacheResult(URL string, r Result) error
+	// Lookup returns cached entry for URL (nil if there is none) and whether it's within its validity threshold.
+	// Expired entries with ETag or LastModified can be revalidated with conditional request.
+	Lookup(URL string) (_ *Entry, fresh bool, _ error)
+	// Put stores entry as is, including its timestamp e.g. when importing cache.
+	Put(URL string, e Entry) error
+	// Entries returns all cached entries by URL.
+	Entries() (map[string]Entry, error)
+	// DeleteURL deletes URL from cache.
+	DeleteURL(URL string) error
+	// Clear removes all entries from cache.
+	Clear() error
+	// Close releases resources and persists cache, if needed.
+	Close() error
+}
+
+// ErrorClass classifies failed link checks.
+type ErrorClass string
+
+const (
+	// ErrorClassNone means link check succeeded.
+	ErrorClassNone = ErrorClass("")
+	// ErrorClassStatus means server responded with not accepted status code.
+	ErrorClassStatus = ErrorClass("status")
+	// ErrorClassTimeout means request timed out.
+	ErrorClassTimeout = ErrorClass("timeout")
+	// ErrorClassNetwork means request failed on connection level e.g. DNS, TLS or connection refused.
+	ErrorClassNetwork = ErrorClass("network")
+)
+
+// Result is an outcome of link check.
+type Result struct {
+	// StatusCode of the response, 0 if there was no response.
+	StatusCode int
+	// ErrorClass of the failure, empty for success.
+	ErrorClass ErrorClass
+	// ETag and LastModified are response headers, allowing conditional requests when entry expires.
+	ETag         string
+	LastModified string
+}
+
+// Entry is a cached Result.
+type Entry struct {
+	Result
+	Timestamp time.Time
+}
+
+// NewStorage returns Storage with backend selected by type from Config passed to Init. Backends persisting cache use
+// given path (file backend appends ".json" to it). If clear is true, persisted cache is dropped on Init.
+func NewStorage(path string, clear bool) Storage {
+	return &typedStorage{path: path, clear: clear}
+}
+
+// typedStorage delegates to backend chosen on Init.
+type typedStorage struct {
+	path  string
+	clear bool
+
+	Storage
+}
+
+func (s *typedStorage) Init(cfg Config) error {
+	if s.Storage != nil {
+		return errors.New("storage already initialized")
+	}
+	switch cfg.cacheType {
+	case cacheTypeSQLite:
+		s.Storage = &SQLite3Storage{Filename: s.path, ClearCache: s.clear}
+	case cacheTypeFile:
+		s.Storage = &FileStorage{Filename: s.path + ".json", ClearCache: s.clear}
+	case cacheTypeMemory:
+		s.Storage = &MemoryStorage{}
+	default:
+		return fmt.Errorf("cache type %q does not have storage", cfg.cacheType)
+	}
+	return s.Storage.Init(cfg)
+}
+
+func (s *typedStorage) Close() error {
+	if s.Storage == nil {
+		// Never initialized, e.g. cache was not configured.
+		return nil
+	}
+	return s.Storage.Close()
+}
+
+// expiry decides validity of cached entries. It's safe for concurrent use.
+type expiry struct {
+	cfg Config
+
+	mu sync.Mutex
+	// Rand for jitter.
+	r *rand.Rand
+}
+
+func newExpiry(cfg Config) *expiry {
+	return &expiry{cfg: cfg, r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// validity returns validity of given result. Status code specific validity takes precedence over error class one.
+func (e *expiry) validity(r Result) (time.Duration, bool) {
+	if r.ErrorClass == ErrorClassNone {
+		return e.cfg.Validity, true
+	}
+	if v, ok := e.cfg.FailureValidity[strconv.Itoa(r.StatusCode)]; ok && r.StatusCode != 0 {
+		return v, true
+	}
+	v, ok := e.cfg.FailureValidity[string(r.ErrorClass)]
+	return v, ok
+}
+
+// cacheable returns true if given result should be cached.
+func (e *expiry) cacheable(r Result) bool {
+	_, ok := e.validity(r)
+	return ok
+}
+
+// isFresh returns true if entry is within its validity threshold.
+func (e *expiry) isFresh(entry Entry) bool {
+	validity, ok := e.validity(entry.Result)
+	if !ok || entry.Timestamp.IsZero() {
+		return false
+	}
+
+	// Check if URL is within validity threshold with jitter (0 is no jitter provided or rand(0->jitter)).
+	jitterValue := time.Duration(0)
+	if e.cfg.Jitter != time.Duration(0) {
+		e.mu.Lock()
+		jitterValue = time.Duration(e.r.Intn(int(e.cfg.Jitter)))
+		e.mu.Unlock()
+	}
+	return time.Since(entry.Timestamp)+jitterValue <= validity
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"gopkg.in/yaml.v3"
+)
+
+func parseConfig(t *testing.T, s string) Config {
+	t.Helper()
+
+	cfg := NewConfig()
+	testutil.Ok(t, yaml.Unmarshal([]byte(s), &cfg))
+	return cfg
+}
+
+func TestStorage(t *testing.T) {
+	for _, typ := range []string{"sqlite", "file", "memory"} {
+		t.Run(typ, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".mdoxcache")
+			cfg := parseConfig(t, "type: "+typ+"\nvalidity: 1h\nfailureValidity:\n  '404': 1h\n")
+
+			s := NewStorage(path, false)
+			testutil.Ok(t, s.Init(cfg))
+
+			testutil.Ok(t, s.CacheResult("https://example.com/ok", Result{StatusCode: 200, ETag: `"abc"`}))
+			testutil.Ok(t, s.CacheResult("https://example.com/404", Result{StatusCode: 404, ErrorClass: ErrorClassStatus}))
+			// No validity configured for this failure, so it's not cached.
+			testutil.Ok(t, s.CacheResult("https://example.com/500", Result{StatusCode: 500, ErrorClass: ErrorClassStatus}))
+
+			e, fresh, err := s.Lookup("https://example.com/ok")
+			testutil.Ok(t, err)
+			testutil.Assert(t, fresh)
+			testutil.Equals(t, Result{StatusCode: 200, ETag: `"abc"`}, e.Result)
+
+			e, fresh, err = s.Lookup("https://example.com/404")
+			testutil.Ok(t, err)
+			testutil.Assert(t, fresh)
+			testutil.Equals(t, ErrorClassStatus, e.ErrorClass)
+
+			e, _, err = s.Lookup("https://example.com/500")
+			testutil.Ok(t, err)
+			testutil.Assert(t, e == nil)
+
+			testutil.Ok(t, s.DeleteURL("https://example.com/404"))
+			e, _, err = s.Lookup("https://example.com/404")
+			testutil.Ok(t, err)
+			testutil.Assert(t, e == nil)
+			testutil.Ok(t, s.Close())
+
+			// Cache is persisted, except for memory backend.
+			s = NewStorage(path, false)
+			testutil.Ok(t, s.Init(cfg))
+			e, _, err = s.Lookup("https://example.com/ok")
+			testutil.Ok(t, err)
+			testutil.Equals(t, typ != "memory", e != nil)
+			testutil.Ok(t, s.Close())
+
+			// Clear on Init drops persisted cache.
+			s = NewStorage(path, true)
+			testutil.Ok(t, s.Init(cfg))
+			e, _, err = s.Lookup("https://example.com/ok")
+			testutil.Ok(t, err)
+			testutil.Assert(t, e == nil)
+			testutil.Ok(t, s.Close())
+		})
+	}
+}
+
+func TestFileStorage_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".mdoxcache.json")
+	testutil.Ok(t, os.WriteFile(path, []byte("{not json"), 0600))
+
+	s := &FileStorage{Filename: path}
+	testutil.NotOk(t, s.Init(parseConfig(t, "type: file\n")))
+}
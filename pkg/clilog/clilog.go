@@ -189,6 +189,7 @@ func (enc *Encoder) EndRecord() error {
 	_, err := enc.w.Write(newline)
 	if err == nil {
 		enc.needSep = false
+		enc.errs = nil
 	}
 	return err
 }
@@ -196,6 +197,7 @@ func (enc *Encoder) EndRecord() error {
 // Reset resets the Encoder to the beginning of a new record.
 func (enc *Encoder) Reset() {
 	enc.needSep = false
+	enc.errs = nil
 }
 
 // MarshalerError represents an error encountered while marshaling a value.
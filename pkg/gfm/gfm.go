@@ -0,0 +1,26 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+// Package gfm contains helpers for GitHub Flavored Markdown generated by mdox.
+package gfm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// HeaderID returns anchor ID GitHub generates for given header text. Text is lowercased, spaces are replaced with '-'
+// and all characters except word characters (letters, marks, digits, connector punctuation like '_') and '-' are
+// removed.
+func HeaderID(header string) string {
+	id := strings.Builder{}
+	for _, r := range strings.ToLower(header) {
+		switch {
+		case r == ' ':
+			_, _ = id.WriteRune('-')
+		case r == '-' || unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || unicode.Is(unicode.Pc, r):
+			_, _ = id.WriteRune(r)
+		}
+	}
+	return id.String()
+}
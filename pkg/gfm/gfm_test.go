@@ -0,0 +1,29 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gfm
+
+import (
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestHeaderID(t *testing.T) {
+	for _, tcase := range []struct {
+		header, expected string
+	}{
+		{header: "Config", expected: "config"},
+		{header: "func (c Config) IsSet() bool", expected: "func-c-config-isset-bool"},
+		{header: "yamlgen.testConfig", expected: "yamlgentestconfig"},
+		{header: "snake_case & kebab-case", expected: "snake_case--kebab-case"},
+		{header: "Zażółć gęślą jaźń", expected: "zażółć-gęślą-jaźń"},
+		{header: "Café", expected: "café"},
+		{header: "日本語 123", expected: "日本語-123"},
+		{header: "!?", expected: ""},
+	} {
+		t.Run(tcase.header, func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, HeaderID(tcase.header))
+		})
+	}
+}
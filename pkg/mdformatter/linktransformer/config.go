@@ -30,6 +30,13 @@ type Config struct {
 	// and we have to tell apart 0 from not-present configurations.
 	HostMaxConns *int   `yaml:"host_max_conns"`
 	RandomDelay  string `yaml:"random_delay"`
+	// DisableHeadFirst makes remote links checked with GET requests only. By default, remote links are checked with HEAD
+	// requests, falling back to GET if server responds with 405 or 403. Links with fragment are always checked with GET,
+	// as page content is needed. Use `disableHeadFirst` of Hosts for hosts that mishandle HEAD requests.
+	DisableHeadFirst bool `yaml:"disableHeadFirst"`
+	// Hosts are policies for hosts matching glob keys e.g. "github.com" or "*.example.com". The longest matching glob
+	// wins. The "*" key overrides global defaults.
+	Hosts map[string]HostConfig `yaml:"hosts"`
 
 	timeout     time.Duration
 	randomDelay time.Duration
@@ -37,36 +44,105 @@ type Config struct {
 
 type ValidatorConfig struct {
 	// Regex for type of validator. For `githubPullsIssues` this is: (^http[s]?:\/\/)(www\.)?(github\.com\/){ORG_NAME}\/{REPO_NAME}(\/pull\/|\/issues\/).
+	// For `gitlabIssuesMergeRequests` and `giteaPullsIssues` it has to match links up to the number e.g.
+	// ^https:\/\/gitlab\.com\/{GROUP}\/{PROJECT}\/-\/(issues|merge_requests)\/.
 	Regex string `yaml:"regex"`
-	// By default type is `roundtrip`. Could be `githubPullsIssues` or `ignore`.
-	Type ValidatorType `yaml:"type"`
-	// GitHub repo token to avoid getting rate limited.
+	// By default type is `roundtrip`. Could be `githubPullsIssues`, `gitlabIssuesMergeRequests`, `giteaPullsIssues`,
+	// `ignore` or `localRepo`.
+	Type ValidatorType `yaml:"type" jsonschema:"enum=roundtrip,enum=githubPullsIssues,enum=gitlabIssuesMergeRequests,enum=giteaPullsIssues,enum=ignore,enum=localRepo"`
+	// GitHub, GitLab or Gitea repo token to avoid getting rate limited or to access private repositories.
 	Token string `yaml:"token"`
+	// Repo is the repository for `gitlabIssuesMergeRequests` (project path with groups e.g. "group/subgroup/project")
+	// and `giteaPullsIssues` (e.g. "owner/repo") types.
+	Repo string `yaml:"repo"`
+	// APIURL is the base URL of API for `gitlabIssuesMergeRequests` (defaults to https://gitlab.com/api/v4) and
+	// `giteaPullsIssues` (defaults to https://gitea.com/api/v1) types, so self-hosted instances can be used. For
+	// precise `githubPullsIssues` it is the GraphQL endpoint (defaults to https://api.github.com/graphql).
+	APIURL string `yaml:"apiURL"`
+	// Precise makes `githubPullsIssues` check that each referenced issue, pull request and issue comment exists, with
+	// batched GraphQL queries, instead of comparing numbers with the latest one. Results are cached in the link cache.
+	Precise bool `yaml:"precise"`
+	// Severity of failed links matching Regex. By default it's `error`. Failed links with `warn` severity are reported,
+	// but do not fail validation, unless validator fails on warnings too.
+	Severity Severity `yaml:"severity" jsonschema:"enum=error,enum=warn"`
+	// AcceptStatusCodes are additional (non 2xx) status codes treated as valid for `roundtrip` type, e.g. 403 for
+	// Cloudflare protected sites or 429. Links accepted this way are not cached.
+	AcceptStatusCodes []int `yaml:"acceptStatusCodes"`
+	// Headers are added to requests of links matching Regex e.g. to access private docs portals. Values can reference
+	// environment variables e.g. "$TOKEN" or "${TOKEN}". Authorization headers and values of environment variables are
+	// redacted from logs and errors.
+	Headers map[string]string `yaml:"headers"`
+	// BasicAuth credentials are added to requests of links matching Regex.
+	BasicAuth *BasicAuthConfig `yaml:"basicAuth"`
+	// BearerToken is added as Authorization header to requests of links matching Regex. It can reference environment
+	// variable e.g. "$TOKEN".
+	BearerToken string `yaml:"bearerToken"`
+	// RepoURL of GitHub or GitLab repository for `localRepo` type e.g. https://github.com/bwplotka/mdox. Its blob and
+	// tree links are checked against LocalDir. Regex defaults to match them.
+	RepoURL string `yaml:"repoURL"`
+	// LocalDir is the local checkout of RepoURL for `localRepo` type, relative to anchor directory. Defaults to anchor
+	// directory.
+	LocalDir string `yaml:"localDir"`
+	// Refs are branches checked against LocalDir for `localRepo` type. Links to other refs (e.g. tags) are checked with
+	// roundtrip. Defaults to main and master.
+	Refs []string `yaml:"refs"`
 
+	headers     http.Header
 	ghValidator GitHubPullsIssuesValidator
 	rtValidator RoundTripValidator
 	igValidator IgnoreValidator
+	lrValidator LocalRepoValidator
+	glValidator GitLabIssuesMergeRequestsValidator
+	gtValidator GiteaPullsIssuesValidator
+}
+
+// BasicAuthConfig are credentials for HTTP basic authentication. Both can reference environment variables e.g. "$PASSWORD".
+type BasicAuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
 }
 
 type RoundTripValidator struct {
-	_regex *regexp.Regexp
+	_regex             *regexp.Regexp
+	_acceptStatusCodes []int
 }
 
 type GitHubPullsIssuesValidator struct {
-	_regex  *regexp.Regexp
-	_maxNum int
+	_regex   *regexp.Regexp
+	_maxNum  int
+	_graphql *gitHubGraphQL
 }
 
 type IgnoreValidator struct {
 	_regex *regexp.Regexp
 }
 
+type LocalRepoValidator struct {
+	_regex    *regexp.Regexp
+	_repoURL  string
+	_localDir string
+	_refs     []string
+}
+
+// Severity of failed link.
+type Severity string
+
+const (
+	// SeverityError fails validation.
+	SeverityError Severity = "error"
+	// SeverityWarn is reported, but fails validation only if validator fails on warnings.
+	SeverityWarn Severity = "warn"
+)
+
 type ValidatorType string
 
 const (
 	roundtripValidator         ValidatorType = "roundtrip"
 	githubPullsIssuesValidator ValidatorType = "githubPullsIssues"
 	ignoreValidator            ValidatorType = "ignore"
+	localRepoValidator         ValidatorType = "localRepo"
+	gitlabValidator            ValidatorType = "gitlabIssuesMergeRequests"
+	giteaValidator             ValidatorType = "giteaPullsIssues"
 )
 
 const (
@@ -78,11 +154,17 @@ type GitHubResponse struct {
 }
 
 func ParseConfig(c []byte) (Config, error) {
+	return parseConfig(c, false)
+}
+
+// parseConfig parses configuration. If offline, no requests are made e.g. to GitHub API.
+func parseConfig(c []byte, offline bool) (Config, error) {
 	cfg := Config{Cache: cache.NewConfig()}
 	dec := yaml.NewDecoder(bytes.NewReader(c))
 	dec.KnownFields(true)
 	if err := dec.Decode(&cfg); err != nil {
-		return Config{}, fmt.Errorf("parsing YAML content %q: %w", string(c), err)
+		// Content is not included, as it might contain credentials.
+		return Config{}, fmt.Errorf("parsing YAML content: %w", err)
 	}
 
 	if cfg.Timeout != "" {
@@ -105,18 +187,66 @@ func ParseConfig(c []byte) (Config, error) {
 		return Config{}, errors.New("parsing parallelism, has to be > 0")
 	}
 
+	for hostGlob, h := range cfg.Hosts {
+		if err := h.parse(hostGlob); err != nil {
+			return Config{}, err
+		}
+		cfg.Hosts[hostGlob] = h
+	}
+
 	if len(cfg.Validators) <= 0 {
 		return cfg, nil
 	}
 
+	// Shared by API requests of all validators.
+	apiClient := newAPIClient(newHostPolicies(cfg), newTransport(cfg))
+
 	// Evaluate regex for given validators.
 	for i := range cfg.Validators {
+		headers, err := cfg.Validators[i].parseHeaders()
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing credentials of validator %v: %w", cfg.Validators[i].Regex, err)
+		}
+		cfg.Validators[i].headers = headers
+
+		if len(cfg.Validators[i].AcceptStatusCodes) > 0 && cfg.Validators[i].Type != roundtripValidator {
+			return Config{}, fmt.Errorf("acceptStatusCodes is supported only for %v validator, got %v", roundtripValidator, cfg.Validators[i].Type)
+		}
+		switch cfg.Validators[i].Severity {
+		case "":
+			cfg.Validators[i].Severity = SeverityError
+		case SeverityError, SeverityWarn:
+		default:
+			return Config{}, fmt.Errorf("unsupported severity %q of validator %v", cfg.Validators[i].Severity, cfg.Validators[i].Regex)
+		}
+		if cfg.Validators[i].Precise && cfg.Validators[i].Type != githubPullsIssuesValidator {
+			return Config{}, fmt.Errorf("precise is supported only for %v validator, got %v", githubPullsIssuesValidator, cfg.Validators[i].Type)
+		}
 		switch cfg.Validators[i].Type {
 		case roundtripValidator:
 			cfg.Validators[i].rtValidator._regex = regexp.MustCompile(cfg.Validators[i].Regex)
+			cfg.Validators[i].rtValidator._acceptStatusCodes = cfg.Validators[i].AcceptStatusCodes
 		case githubPullsIssuesValidator:
+			if cfg.Validators[i].Precise {
+				// Links are looked up when checked, so latest number is not needed.
+				owner, name, err := getGitHubRepo(cfg.Validators[i].Regex)
+				if err != nil {
+					return Config{}, fmt.Errorf("parsing githubPullsIssues Regex: %w", err)
+				}
+				cfg.Validators[i].ghValidator._regex = regexp.MustCompile(cfg.Validators[i].Regex)
+				cfg.Validators[i].ghValidator._graphql = newGitHubGraphQL(cfg.Validators[i], owner, name)
+				break
+			}
+			if offline {
+				// Links are not validated against GitHub, regex is needed only to match them.
+				cfg.Validators[i].ghValidator._regex, err = regexp.Compile(cfg.Validators[i].Regex)
+				if err != nil {
+					return Config{}, fmt.Errorf("parsing githubPullsIssues Regex: %w", err)
+				}
+				break
+			}
 			// Get maxNum from provided regex or fail.
-			regex, maxNum, err := getGitHubRegex(cfg.Validators[i].Regex, cfg.Validators[i].Token)
+			regex, maxNum, err := getGitHubRegex(apiClient, cfg.Validators[i].Regex, cfg.Validators[i].Token, cfg.Validators[i].headers)
 			if err != nil {
 				return Config{}, fmt.Errorf("parsing githubPullsIssues Regex: %w", err)
 			}
@@ -124,6 +254,24 @@ func ParseConfig(c []byte) (Config, error) {
 			cfg.Validators[i].ghValidator._maxNum = maxNum
 		case ignoreValidator:
 			cfg.Validators[i].igValidator._regex = regexp.MustCompile(cfg.Validators[i].Regex)
+		case gitlabValidator:
+			gl, err := newGitLabValidator(apiClient, cfg.Validators[i], offline)
+			if err != nil {
+				return Config{}, fmt.Errorf("parsing gitlabIssuesMergeRequests validator: %w", err)
+			}
+			cfg.Validators[i].glValidator = gl
+		case giteaValidator:
+			gt, err := newGiteaValidator(apiClient, cfg.Validators[i], offline)
+			if err != nil {
+				return Config{}, fmt.Errorf("parsing giteaPullsIssues validator: %w", err)
+			}
+			cfg.Validators[i].gtValidator = gt
+		case localRepoValidator:
+			lr, err := newLocalRepoValidator(cfg.Validators[i])
+			if err != nil {
+				return Config{}, fmt.Errorf("parsing localRepo validator: %w", err)
+			}
+			cfg.Validators[i].lrValidator = lr
 		default:
 			return Config{}, errors.New("Validator type not supported")
 		}
@@ -131,22 +279,29 @@ func ParseConfig(c []byte) (Config, error) {
 	return cfg, nil
 }
 
-// getGitHubRegex returns GitHub pulls/issues regex from repo name.
-func getGitHubRegex(pullsIssuesRe string, repoToken string) (*regexp.Regexp, int, error) {
+// getGitHubRepo returns GitHub org and repo name from pulls/issues regex.
+func getGitHubRepo(pullsIssuesRe string) (org string, repo string, _ error) {
 	// Get reponame from Pulls & Issues regex. This also checks whether user provided regex is valid (inception again!).
 	getRepo := regexp.MustCompile(`\(\^http\[s\]\?:\\\/\\\/\)\(www\\\.\)\?\(github\\\.com\\\/\)(?P<org>[A-Za-z0-9_.-]+)\\\/(?P<repo>[A-Za-z0-9_.-]+)\(\\\/pull\\\/\|\\\/issues\\\/\)`)
 	match := getRepo.FindStringSubmatch(pullsIssuesRe)
 	if len(match) != 3 {
-		return nil, math.MaxInt64, errors.New(`GitHub PR/Issue regex not valid. Correct regex: (^http[s]?:\/\/)(www\.)?(github\.com\/){ORG_NAME}\/{REPO_NAME}(\/pull\/|\/issues\/)`)
+		return "", "", errors.New(`GitHub PR/Issue regex not valid. Correct regex: (^http[s]?:\/\/)(www\.)?(github\.com\/){ORG_NAME}\/{REPO_NAME}(\/pull\/|\/issues\/)`)
 	}
-	reponame := match[1] + "/" + match[2]
+	return match[1], match[2], nil
+}
+
+// getGitHubRegex returns GitHub pulls/issues regex from repo name.
+func getGitHubRegex(client *http.Client, pullsIssuesRe string, repoToken string, headers http.Header) (*regexp.Regexp, int, error) {
+	org, repo, err := getGitHubRepo(pullsIssuesRe)
+	if err != nil {
+		return nil, math.MaxInt64, err
+	}
+	reponame := org + "/" + repo
 
 	var pullNum []GitHubResponse
 	var issueNum []GitHubResponse
 	max := 0
 	// All GitHub API reqs need to have User-Agent: https://docs.github.com/en/rest/overview/resources-in-the-rest-api#user-agent-required.
-	client := &http.Client{}
-
 	// Check latest pull request number.
 	reqPull, err := http.NewRequest("GET", fmt.Sprintf(gitHubAPIURL, reponame, "pulls"), nil)
 	if err != nil {
@@ -161,6 +316,10 @@ func getGitHubRegex(pullsIssuesRe string, repoToken string) (*regexp.Regexp, int
 	}
 	reqIssue.Header.Set("User-Agent", "mdox")
 
+	for k, vals := range headers {
+		reqPull.Header[k] = vals
+		reqIssue.Header[k] = vals
+	}
 	if repoToken != "" {
 		reqPull.Header.Set("Authorization", "Bearer "+repoToken)
 		reqIssue.Header.Set("Authorization", "Bearer "+repoToken)
@@ -0,0 +1,191 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultGitLabAPIURL = "https://gitlab.com/api/v4"
+	defaultGiteaAPIURL  = "https://gitea.com/api/v1"
+)
+
+type GitLabIssuesMergeRequestsValidator struct {
+	_regex           *regexp.Regexp
+	_maxIssue        int
+	_maxMergeRequest int
+}
+
+type GiteaPullsIssuesValidator struct {
+	_regex  *regexp.Regexp
+	_maxNum int
+}
+
+// GitLabResponse is a single issue or merge request returned by GitLab API.
+type GitLabResponse struct {
+	IID int `json:"iid"`
+}
+
+// GiteaResponse is a single issue or pull request returned by Gitea API.
+type GiteaResponse struct {
+	Number int `json:"number"`
+}
+
+func newGitLabValidator(client *http.Client, c ValidatorConfig, offline bool) (GitLabIssuesMergeRequestsValidator, error) {
+	re, err := forgeRegex(c)
+	if err != nil {
+		return GitLabIssuesMergeRequestsValidator{}, err
+	}
+	v := GitLabIssuesMergeRequestsValidator{_regex: re}
+	if offline {
+		// Links are not validated against GitLab, regex is needed only to match them.
+		return v, nil
+	}
+
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = defaultGitLabAPIURL
+	}
+	h := http.Header{}
+	if c.Token != "" {
+		h.Set("PRIVATE-TOKEN", c.Token)
+	}
+	project := strings.TrimSuffix(apiURL, "/") + "/projects/" + url.PathEscape(c.Repo)
+
+	// Issues and merge requests are numbered separately in GitLab.
+	var issues, mergeRequests []GitLabResponse
+	if err := getForgeJSON(client, project+"/issues?order_by=created_at&sort=desc&per_page=1", c.headers, h, &issues); err != nil {
+		return GitLabIssuesMergeRequestsValidator{}, fmt.Errorf("issues API request: %w", err)
+	}
+	if err := getForgeJSON(client, project+"/merge_requests?order_by=created_at&sort=desc&per_page=1", c.headers, h, &mergeRequests); err != nil {
+		return GitLabIssuesMergeRequestsValidator{}, fmt.Errorf("merge requests API request: %w", err)
+	}
+	if len(issues) > 0 {
+		v._maxIssue = issues[0].IID
+	}
+	if len(mergeRequests) > 0 {
+		v._maxMergeRequest = mergeRequests[0].IID
+	}
+	return v, nil
+}
+
+func newGiteaValidator(client *http.Client, c ValidatorConfig, offline bool) (GiteaPullsIssuesValidator, error) {
+	re, err := forgeRegex(c)
+	if err != nil {
+		return GiteaPullsIssuesValidator{}, err
+	}
+	v := GiteaPullsIssuesValidator{_regex: re}
+	if offline {
+		// Links are not validated against Gitea, regex is needed only to match them.
+		return v, nil
+	}
+
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = defaultGiteaAPIURL
+	}
+	h := http.Header{}
+	if c.Token != "" {
+		h.Set("Authorization", "token "+c.Token)
+	}
+	issuesURL := strings.TrimSuffix(apiURL, "/") + "/repos/" + c.Repo + "/issues?state=all&limit=1&type="
+
+	// Pull requests and issues share numbering in Gitea, so the greater number is the latest.
+	for _, typ := range []string{"issues", "pulls"} {
+		var resp []GiteaResponse
+		if err := getForgeJSON(client, issuesURL+typ, c.headers, h, &resp); err != nil {
+			return GiteaPullsIssuesValidator{}, fmt.Errorf("%v API request: %w", typ, err)
+		}
+		if len(resp) > 0 && resp[0].Number > v._maxNum {
+			v._maxNum = resp[0].Number
+		}
+	}
+	return v, nil
+}
+
+func forgeRegex(c ValidatorConfig) (*regexp.Regexp, error) {
+	if c.Regex == "" {
+		return nil, errors.New("regex is required")
+	}
+	if c.Repo == "" {
+		return nil, errors.New("repo is required")
+	}
+	return regexp.Compile(c.Regex)
+}
+
+// getForgeJSON decodes JSON response of GET request to API URL, with validator and API headers.
+func getForgeJSON(client *http.Client, apiURL string, headers http.Header, apiHeaders http.Header, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "mdox")
+	for k, vals := range headers {
+		req.Header[k] = vals
+	}
+	for k, vals := range apiHeaders {
+		req.Header[k] = vals
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// numberAfterMatch returns issue or pull request number following regex match in link.
+func numberAfterMatch(re *regexp.Regexp, dest string) (int, error) {
+	// Number ends with section, query or path e.g. /diffs.
+	s := dest[re.FindStringIndex(dest)[1]:]
+	if i := strings.IndexAny(s, "#?/"); i >= 0 {
+		s = s[:i]
+	}
+	return strconv.Atoi(s)
+}
+
+// GitLabIssuesMergeRequestsValidator.IsValid checks GitLab issue and merge request links against latest numbers,
+// without visiting them.
+func (v GitLabIssuesMergeRequestsValidator) IsValid(k futureKey, r *validator) (bool, error) {
+	r.l.gitlabSkippedLinks.Inc()
+	max := v._maxIssue
+	if strings.Contains(k.dest, "/merge_requests/") {
+		max = v._maxMergeRequest
+	}
+	return checkForgeNumber(v._regex, max, k, r)
+}
+
+// GiteaPullsIssuesValidator.IsValid checks Gitea pull request and issue links against latest number, without
+// visiting them.
+func (v GiteaPullsIssuesValidator) IsValid(k futureKey, r *validator) (bool, error) {
+	r.l.giteaSkippedLinks.Inc()
+	return checkForgeNumber(v._regex, v._maxNum, k, r)
+}
+
+func checkForgeNumber(re *regexp.Regexp, max int, k futureKey, r *validator) (bool, error) {
+	num, err := numberAfterMatch(re, k.dest)
+	if err != nil {
+		r.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, parsing number: %w", k.dest, err) }
+		return false, err
+	}
+	if num < 1 || num > max {
+		r.destFutures[k].resultFn = func() error {
+			return fmt.Errorf("link %v, number %v does not exist, latest is %v", k.dest, num, max)
+		}
+		return false, nil
+	}
+	return true, nil
+}
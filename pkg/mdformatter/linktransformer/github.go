@@ -0,0 +1,375 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwplotka/mdox/pkg/cache"
+)
+
+const (
+	defaultGitHubGraphQLURL = "https://api.github.com/graphql"
+	// gitHubGraphQLBatch is the maximum number of issues and pull requests looked up in a single query.
+	gitHubGraphQLBatch = 100
+)
+
+var issueCommentRe = regexp.MustCompile(`^issuecomment-(\d+)$`)
+
+// gitHubGraphQL checks existence of issues, pull requests and their comments referenced by links. Links are collected
+// while file is processed and looked up in batches per repository when it's closed.
+type gitHubGraphQL struct {
+	apiURL  string
+	headers http.Header
+	owner   string
+	name    string
+
+	mu sync.Mutex
+	// pending are numbers to look up, with comments to look up for them.
+	pending map[int]map[int64]struct{}
+	// types are looked up __typename by number, empty if issue or pull request does not exist.
+	types map[int]string
+	// comments are existing comment IDs by number, for numbers with looked up comments.
+	comments map[int]map[int64]struct{}
+	// errs are lookup failures by number e.g. API errors.
+	errs map[int]error
+}
+
+func newGitHubGraphQL(c ValidatorConfig, owner, name string) *gitHubGraphQL {
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = defaultGitHubGraphQLURL
+	}
+	headers := http.Header{}
+	for k, vals := range c.headers {
+		headers[k] = vals
+	}
+	if c.Token != "" {
+		headers.Set("Authorization", "Bearer "+c.Token)
+	}
+	return &gitHubGraphQL{
+		apiURL:   apiURL,
+		headers:  headers,
+		owner:    owner,
+		name:     name,
+		pending:  map[int]map[int64]struct{}{},
+		types:    map[int]string{},
+		comments: map[int]map[int64]struct{}{},
+		errs:     map[int]error{},
+	}
+}
+
+// gitHubRef is an issue or pull request (and optionally its comment) referenced by link.
+type gitHubRef struct {
+	// page is link up to the number e.g. https://github.com/bwplotka/mdox/pull/32.
+	page    string
+	pull    bool
+	num     int
+	comment int64
+}
+
+func (r gitHubRef) cacheKey() string {
+	if r.comment != 0 {
+		return fmt.Sprintf("%v#issuecomment-%v", r.page, r.comment)
+	}
+	return r.page
+}
+
+func (v GitHubPullsIssuesValidator) parseRef(dest string) (gitHubRef, error) {
+	loc := v._regex.FindStringIndex(dest)
+	num, err := numberAfterMatch(v._regex, dest)
+	if err != nil {
+		return gitHubRef{}, err
+	}
+	ref := gitHubRef{
+		page: dest[:loc[1]] + strconv.Itoa(num),
+		pull: strings.HasSuffix(dest[:loc[1]], "/pull/"),
+		num:  num,
+	}
+	if _, fragment := splitFragment(dest); fragment != "" {
+		if m := issueCommentRe.FindStringSubmatch(fragment); m != nil {
+			if ref.comment, err = strconv.ParseInt(m[1], 10, 64); err != nil {
+				return gitHubRef{}, err
+			}
+		}
+	}
+	return ref, nil
+}
+
+// checkPrecise registers link to be looked up when file is closed, unless its result is cached.
+func (v GitHubPullsIssuesValidator) checkPrecise(k futureKey, r *validator) (bool, error) {
+	ref, err := v.parseRef(k.dest)
+	if err != nil {
+		r.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, parsing number: %w", k.dest, err) }
+		return false, err
+	}
+
+	if r.storage != nil {
+		if e, fresh, err := r.storage.Lookup(ref.cacheKey()); err == nil && e != nil && fresh {
+			r.l.githubCachedLinks.Inc()
+			if e.ErrorClass != cache.ErrorClassNone {
+				r.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, %w", k.dest, cachedFailureErr(ref.cacheKey(), e)) }
+				return false, nil
+			}
+			return true, nil
+		}
+	}
+
+	g := v._graphql
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.pending[ref.num]; !ok {
+		g.pending[ref.num] = map[int64]struct{}{}
+	}
+	if ref.comment != 0 {
+		g.pending[ref.num][ref.comment] = struct{}{}
+	}
+	r.destFutures[k].resultFn = func() error {
+		if err := g.result(r, ref); err != nil {
+			return fmt.Errorf("link %v, %w", k.dest, err)
+		}
+		return nil
+	}
+	return true, nil
+}
+
+// result returns error if referenced issue, pull request or comment does not exist and caches the result. It has to be
+// called after flush.
+func (g *gitHubGraphQL) result(r *validator, ref gitHubRef) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.errs[ref.num]; err != nil {
+		// Not cached, so it's retried on the next run.
+		return err
+	}
+	if err := g.check(ref); err != nil {
+		r.cacheResult(ref.cacheKey(), cache.Result{StatusCode: http.StatusNotFound, ErrorClass: cache.ErrorClassStatus})
+		return err
+	}
+	r.cacheResult(ref.cacheKey(), cache.Result{StatusCode: http.StatusOK})
+	return nil
+}
+
+// check returns error if referenced issue, pull request or comment is not among looked up ones.
+// NOTE: mu has to be held by the caller.
+func (g *gitHubGraphQL) check(ref gitHubRef) error {
+	switch typ := g.types[ref.num]; {
+	case typ == "":
+		return fmt.Errorf("issue or pull request %v/%v#%v does not exist", g.owner, g.name, ref.num)
+	case ref.pull && typ != "PullRequest":
+		return fmt.Errorf("%v/%v#%v is an issue, not a pull request", g.owner, g.name, ref.num)
+	case !ref.pull && typ == "PullRequest":
+		return fmt.Errorf("%v/%v#%v is a pull request, not an issue", g.owner, g.name, ref.num)
+	}
+	if ref.comment != 0 {
+		if _, ok := g.comments[ref.num][ref.comment]; !ok {
+			return fmt.Errorf("comment %v of %v/%v#%v does not exist", ref.comment, g.owner, g.name, ref.num)
+		}
+	}
+	return nil
+}
+
+// flush looks up all pending numbers, in batches. Lookups are made without holding mu.
+func (g *gitHubGraphQL) flush(r *validator) {
+	g.mu.Lock()
+	pending := g.pending
+	g.pending = map[int]map[int64]struct{}{}
+	nums := make([]int, 0, len(pending))
+	for num := range pending {
+		if _, ok := g.types[num]; !ok {
+			nums = append(nums, num)
+		}
+	}
+	g.mu.Unlock()
+
+	sort.Ints(nums)
+	for len(nums) > 0 {
+		batch := nums
+		if len(batch) > gitHubGraphQLBatch {
+			batch = batch[:gitHubGraphQLBatch]
+		}
+		nums = nums[len(batch):]
+
+		r.l.githubGraphQLQueries.Inc()
+		types, err := g.lookupTypes(r, batch)
+		g.mu.Lock()
+		for _, num := range batch {
+			if err != nil {
+				g.errs[num] = err
+				continue
+			}
+			g.types[num] = types[num]
+		}
+		g.mu.Unlock()
+	}
+
+	g.mu.Lock()
+	var commentNums []int
+	for num, comments := range pending {
+		if len(comments) == 0 || g.types[num] == "" || g.errs[num] != nil {
+			continue
+		}
+		if _, ok := g.comments[num]; ok {
+			// Comments added later are not listed, but it's unlikely they are linked in the same run.
+			continue
+		}
+		commentNums = append(commentNums, num)
+	}
+	g.mu.Unlock()
+
+	for _, num := range commentNums {
+		ids, err := g.lookupComments(r, num)
+		g.mu.Lock()
+		if err != nil {
+			g.errs[num] = err
+		} else {
+			g.comments[num] = ids
+		}
+		g.mu.Unlock()
+	}
+}
+
+type gitHubGraphQLResponse struct {
+	Data struct {
+		Repository map[string]json.RawMessage `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// lookupTypes returns __typename of issues and pull requests by number. Not existing numbers are skipped.
+func (g *gitHubGraphQL) lookupTypes(r *validator, nums []int) (map[int]string, error) {
+	var b strings.Builder
+	b.WriteString("query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) {")
+	for _, num := range nums {
+		fmt.Fprintf(&b, " n%[1]v: issueOrPullRequest(number: %[1]v) { __typename }", num)
+	}
+	b.WriteString(" } }")
+
+	resp, err := g.query(r.apiClient, b.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	types := map[int]string{}
+	for _, num := range nums {
+		raw, ok := resp.Data.Repository[fmt.Sprintf("n%v", num)]
+		if !ok {
+			continue
+		}
+		var node *struct {
+			Typename string `json:"__typename"`
+		}
+		if err := json.Unmarshal(raw, &node); err != nil {
+			return nil, fmt.Errorf("decoding GitHub GraphQL response: %w", err)
+		}
+		if node != nil {
+			types[num] = node.Typename
+		}
+	}
+	return types, nil
+}
+
+// lookupComments returns IDs of all comments of issue or pull request.
+func (g *gitHubGraphQL) lookupComments(r *validator, num int) (map[int64]struct{}, error) {
+	const commentsQuery = `query($owner: String!, $name: String!, $num: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    issueOrPullRequest(number: $num) {
+      ... on Issue { comments(first: 100, after: $cursor) { nodes { databaseId } pageInfo { hasNextPage endCursor } } }
+      ... on PullRequest { comments(first: 100, after: $cursor) { nodes { databaseId } pageInfo { hasNextPage endCursor } } }
+    }
+  }
+}`
+
+	ids := map[int64]struct{}{}
+	var cursor *string
+	for {
+		r.l.githubGraphQLQueries.Inc()
+		resp, err := g.query(r.apiClient, commentsQuery, map[string]interface{}{"num": num, "cursor": cursor})
+		if err != nil {
+			return nil, err
+		}
+		var node *struct {
+			Comments struct {
+				Nodes []struct {
+					DatabaseID int64 `json:"databaseId"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"comments"`
+		}
+		if err := json.Unmarshal(resp.Data.Repository["issueOrPullRequest"], &node); err != nil {
+			return nil, fmt.Errorf("decoding GitHub GraphQL response: %w", err)
+		}
+		if node == nil {
+			return ids, nil
+		}
+		for _, c := range node.Comments.Nodes {
+			ids[c.DatabaseID] = struct{}{}
+		}
+		if !node.Comments.PageInfo.HasNextPage {
+			return ids, nil
+		}
+		endCursor := node.Comments.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+// query runs GraphQL query against repository. Not found errors are expected for not existing numbers, others fail.
+func (g *gitHubGraphQL) query(client *http.Client, query string, variables map[string]interface{}) (*gitHubGraphQLResponse, error) {
+	vars := map[string]interface{}{"owner": g.owner, "name": g.name}
+	for k, v := range variables {
+		vars[k] = v
+	}
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "mdox")
+	req.Header.Set("Content-Type", "application/json")
+	for k, vals := range g.headers {
+		req.Header[k] = vals
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub GraphQL request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub GraphQL request failed. status code: %d", resp.StatusCode)
+	}
+
+	var res gitHubGraphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decoding GitHub GraphQL response: %w", err)
+	}
+	for _, e := range res.Errors {
+		if e.Type != "NOT_FOUND" {
+			return nil, fmt.Errorf("GitHub GraphQL query: %v", e.Message)
+		}
+	}
+	if res.Data.Repository == nil {
+		return nil, errors.New("GitHub GraphQL query: repository not found")
+	}
+	return &res, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+type globalizer struct {
+	logger    log.Logger
+	base      *url.URL
+	anchorDir string
+	// extensions are replacements of linked file extensions e.g. ".md" to ".html".
+	extensions map[string]string
+}
+
+// NewGlobalizer returns mdformatter.LinkTransformer that transforms relative links (and links absolute to anchor dir) to
+// absolute URLs, by resolving path of linked file relative to anchorDir against base URL e.g.
+// https://github.com/bwplotka/mdox/blob/main/. Extensions of linked files are replaced according to given mapping, e.g.
+// ".md" to ".html", for websites built from markdown. It's the inverse of NewLocalizer, useful for publishing files
+// outside of the repository.
+func NewGlobalizer(logger log.Logger, base string, anchorDir string, extensions map[string]string) (mdformatter.LinkTransformer, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base URL %v: %w", base, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("base URL %v has to be absolute", base)
+	}
+	// Without trailing slash, last path element would be replaced.
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &globalizer{logger: logger, base: u, anchorDir: anchorDir, extensions: extensions}, nil
+}
+
+func (g *globalizer) TransformDestination(ctx mdformatter.SourceContext, destination []byte) (_ []byte, err error) {
+	dest := string(destination)
+	if remoteLinkPrefixRe.MatchString(dest) || strings.HasPrefix(dest, "#") || strings.Contains(dest, ":") {
+		return destination, nil
+	}
+
+	page, fragment := splitFragment(dest)
+	page, query, _ := strings.Cut(page, "?")
+	rel, err := filepath.Rel(g.anchorDir, absLocalLink(g.anchorDir, ctx.Filepath, page))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		level.Debug(g.logger).Log("msg", "link outside of anchor dir; skipping globalization", "file", ctx.Filepath, "link", dest)
+		return destination, nil
+	}
+	rel = filepath.ToSlash(rel)
+	if ext := path.Ext(rel); ext != "" {
+		if repl, ok := g.extensions[ext]; ok {
+			rel = strings.TrimSuffix(rel, ext) + repl
+		}
+	}
+	if rel == "." {
+		rel = ""
+	}
+
+	u := g.base.ResolveReference(&url.URL{Path: rel, RawQuery: query, Fragment: fragment})
+	return []byte(u.String()), nil
+}
+
+func (g *globalizer) Close(mdformatter.SourceContext) error { return nil }
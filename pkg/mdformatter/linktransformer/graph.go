@@ -0,0 +1,91 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+)
+
+// LinkGraph is mdformatter.LinkTransformer which collects links between local files, without changing them.
+type LinkGraph struct {
+	anchorDir string
+
+	mu sync.Mutex
+	// links are absolute paths of linked local files or directories by absolute path of linking file.
+	links map[string]map[string]struct{}
+}
+
+// NewLinkGraph returns LinkGraph resolving links absolute to anchorDir.
+func NewLinkGraph(anchorDir string) *LinkGraph {
+	return &LinkGraph{anchorDir: anchorDir, links: map[string]map[string]struct{}{}}
+}
+
+func (g *LinkGraph) TransformDestination(ctx mdformatter.SourceContext, destination []byte) ([]byte, error) {
+	dest := string(destination)
+	if remoteLinkPrefixRe.MatchString(dest) || strings.HasPrefix(dest, "#") || strings.Contains(dest, ":") {
+		return destination, nil
+	}
+	if i := strings.Index(dest, "?"); i >= 0 {
+		dest = dest[:i]
+	}
+	target, _ := splitFragment(absLocalLink(g.anchorDir, ctx.Filepath, dest))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.links[ctx.Filepath]; !ok {
+		g.links[ctx.Filepath] = map[string]struct{}{}
+	}
+	g.links[ctx.Filepath][target] = struct{}{}
+	return destination, nil
+}
+
+func (g *LinkGraph) Close(mdformatter.SourceContext) error { return nil }
+
+// Reachable returns absolute paths of all files and directories reachable from given root files by collected links.
+// Link to directory reaches its README.md too, as it's rendered by GitHub.
+func (g *LinkGraph) Reachable(roots ...string) map[string]struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reachable := map[string]struct{}{}
+	queue := append([]string(nil), roots...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if _, ok := reachable[p]; ok {
+			continue
+		}
+		reachable[p] = struct{}{}
+
+		for target := range g.links[p] {
+			queue = append(queue, target)
+			if info, err := os.Stat(target); err == nil && info.IsDir() {
+				queue = append(queue, filepath.Join(target, "README.md"))
+			}
+		}
+	}
+	return reachable
+}
+
+// Orphans returns sorted files, which are not reachable from given root files by collected links.
+func (g *LinkGraph) Orphans(roots []string, files []string) []string {
+	reachable := g.Reachable(roots...)
+	var orphans []string
+	for _, f := range files {
+		if _, ok := reachable[f]; ok {
+			continue
+		}
+		// Mark as reachable, so duplicated files are listed once.
+		reachable[f] = struct{}{}
+		orphans = append(orphans, f)
+	}
+	sort.Strings(orphans)
+	return orphans
+}
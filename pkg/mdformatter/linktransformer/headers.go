@@ -0,0 +1,240 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+)
+
+const redacted = "<redacted>"
+
+// parseHeaders returns headers to add to requests matching validator, with environment variables expanded.
+func (c ValidatorConfig) parseHeaders() (http.Header, error) {
+	if len(c.Headers) == 0 && c.BasicAuth == nil && c.BearerToken == "" {
+		return nil, nil
+	}
+
+	h := http.Header{}
+	for k, v := range c.Headers {
+		v, err := expandEnv(v)
+		if err != nil {
+			return nil, fmt.Errorf("header %v: %w", k, err)
+		}
+		h.Set(k, v)
+	}
+
+	if c.BasicAuth != nil && c.BearerToken != "" {
+		return nil, errors.New("basicAuth and bearerToken are mutually exclusive")
+	}
+	if c.BasicAuth != nil {
+		username, err := expandEnv(c.BasicAuth.Username)
+		if err != nil {
+			return nil, fmt.Errorf("basic auth username: %w", err)
+		}
+		password, err := expandEnv(c.BasicAuth.Password)
+		if err != nil {
+			return nil, fmt.Errorf("basic auth password: %w", err)
+		}
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	if c.BearerToken != "" {
+		token, err := expandEnv(c.BearerToken)
+		if err != nil {
+			return nil, fmt.Errorf("bearer token: %w", err)
+		}
+		h.Set("Authorization", "Bearer "+token)
+	}
+	return h, nil
+}
+
+// expandEnv replaces $VAR and ${VAR} with environment variable values. Unset variables are errors, so requests are not
+// made with empty credentials.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables %v are not set", missing)
+	}
+	return expanded, nil
+}
+
+// envValues returns values of environment variables referenced by $VAR or ${VAR} in given string.
+func envValues(s string) []string {
+	var values []string
+	os.Expand(s, func(name string) string {
+		v := os.Getenv(name)
+		values = append(values, v)
+		return v
+	})
+	return values
+}
+
+func (c ValidatorConfig) regex() *regexp.Regexp {
+	switch c.Type {
+	case roundtripValidator:
+		return c.rtValidator._regex
+	case githubPullsIssuesValidator:
+		return c.ghValidator._regex
+	case ignoreValidator:
+		return c.igValidator._regex
+	case localRepoValidator:
+		return c.lrValidator._regex
+	case gitlabValidator:
+		return c.glValidator._regex
+	case giteaValidator:
+		return c.gtValidator._regex
+	}
+	return nil
+}
+
+// headersForURL returns headers of the first validator matching URL, same as GetValidatorForURL.
+func (v Config) headersForURL(URL string) http.Header {
+	for _, val := range v.Validators {
+		if re := val.regex(); re != nil && re.MatchString(URL) {
+			return val.headers
+		}
+	}
+	return nil
+}
+
+// severityForURL returns severity of the first validator matching URL, same as GetValidatorForURL.
+func (v Config) severityForURL(URL string) Severity {
+	for _, val := range v.Validators {
+		if re := val.regex(); re != nil && re.MatchString(URL) {
+			return val.Severity
+		}
+	}
+	return SeverityError
+}
+
+// redactor replaces credentials from configuration in logs and errors.
+type redactor struct {
+	r *strings.Replacer
+}
+
+func newRedactor(config Config) *redactor {
+	var secrets []string
+	add := func(s string) {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	for _, val := range config.Validators {
+		add(val.Token)
+		for _, k := range []string{"Authorization", "Proxy-Authorization"} {
+			// Set by headers, basicAuth or bearerToken.
+			for _, v := range val.headers.Values(k) {
+				add(v)
+				if _, cred, ok := strings.Cut(v, " "); ok {
+					// Credentials without scheme e.g. "Bearer".
+					add(cred)
+				}
+			}
+		}
+		for _, v := range val.Headers {
+			// Values of other headers are redacted only if taken from environment, as those are usually API keys.
+			for _, env := range envValues(v) {
+				add(env)
+			}
+		}
+		if val.BasicAuth != nil {
+			if p, err := expandEnv(val.BasicAuth.Password); err == nil {
+				add(p)
+			}
+		}
+	}
+	if len(secrets) == 0 {
+		return &redactor{}
+	}
+
+	// Longer secrets first, so secrets containing others are redacted entirely.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	oldnew := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		oldnew = append(oldnew, s, redacted)
+	}
+	return &redactor{r: strings.NewReplacer(oldnew...)}
+}
+
+func (r *redactor) redact(s string) string {
+	if r.r == nil {
+		return s
+	}
+	return r.r.Replace(s)
+}
+
+// redactErr returns error with credentials redacted from its message. Original error is still available via Unwrap.
+func (r *redactor) redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := r.redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// logger returns logger redacting credentials from logged string and error values.
+func (r *redactor) logger(l log.Logger) log.Logger {
+	if r.r == nil {
+		return l
+	}
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		redactedKeyvals := make([]interface{}, len(keyvals))
+		for i, kv := range keyvals {
+			switch v := kv.(type) {
+			case string:
+				redactedKeyvals[i] = r.redact(v)
+			case error:
+				redactedKeyvals[i] = r.redactErr(v)
+			default:
+				redactedKeyvals[i] = kv
+			}
+		}
+		return l.Log(redactedKeyvals...)
+	})
+}
+
+// headerTransport adds configured headers to requests of matching URLs.
+type headerTransport struct {
+	headers http.Header
+	next    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper should not modify request, so clone it.
+	req = req.Clone(req.Context())
+	for k, vals := range t.headers {
+		req.Header[k] = vals
+	}
+	return t.next.RoundTrip(req)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
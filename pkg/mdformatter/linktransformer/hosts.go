@@ -0,0 +1,357 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	defaultTimeout        = 10 * time.Second
+	defaultParallelism    = 100
+	defaultMaxRetries     = 1
+	defaultBackoffInitial = 1 * time.Second
+	defaultBackoffMax     = 30 * time.Second
+	defaultMaxRetryAfter  = 1 * time.Minute
+)
+
+// HostConfig is a policy for checking links of hosts matching its glob. Unset fields default to global configuration.
+type HostConfig struct {
+	// Parallelism is the maximum number of concurrent requests to all hosts matching the glob together e.g. for
+	// "*.example.com" it's shared by all its subdomains.
+	Parallelism int `yaml:"parallelism"`
+	// Delay is the duration to wait between requests to the host e.g. "1s".
+	Delay string `yaml:"delay"`
+	// RandomDelay is the maximum random duration added to Delay.
+	RandomDelay string `yaml:"randomDelay"`
+	// Timeout of a single request to the host e.g. "30s".
+	Timeout string `yaml:"timeout"`
+	// MaxRetries is the maximum number of retries of a request failed with 429, 503, 307 status code or on
+	// connection level. Defaults to 1. Set to -1 to disable retries.
+	MaxRetries int `yaml:"maxRetries"`
+	// Backoff between retries.
+	Backoff BackoffConfig `yaml:"backoff"`
+	// MaxRetryAfter is the longest Retry-After response header value honored e.g. "5m". Defaults to "1m". Requests
+	// asked to retry later than that are not retried and their links fail.
+	MaxRetryAfter string `yaml:"maxRetryAfter"`
+	// DisableHeadFirst makes links of the host checked with GET requests only, e.g. for hosts responding to HEAD
+	// requests with 404 or 5xx status codes.
+	DisableHeadFirst bool `yaml:"disableHeadFirst"`
+
+	delay         time.Duration
+	randomDelay   time.Duration
+	timeout       time.Duration
+	maxRetryAfter time.Duration
+}
+
+// BackoffConfig is an exponential backoff with jitter. N-th retry waits random duration between half and full of
+// min(Max, Initial * 2^N). Retry-After response header (in seconds or HTTP-date) takes precedence and is honored
+// exactly, up to MaxRetryAfter of the host.
+type BackoffConfig struct {
+	// Initial backoff e.g. "1s". Defaults to "1s".
+	Initial string `yaml:"initial"`
+	// Max backoff e.g. "30s". Defaults to "30s".
+	Max string `yaml:"max"`
+
+	initial time.Duration
+	max     time.Duration
+}
+
+func (h *HostConfig) parse(hostGlob string) error {
+	if _, err := glob.Compile(hostGlob); err != nil {
+		return fmt.Errorf("parsing host glob %q: %w", hostGlob, err)
+	}
+	if h.Parallelism < 0 {
+		return fmt.Errorf("parsing parallelism for host %q, has to be >= 0", hostGlob)
+	}
+	if h.MaxRetries < -1 {
+		return fmt.Errorf("parsing maxRetries for host %q, has to be >= -1", hostGlob)
+	}
+	for _, d := range []struct {
+		name string
+		s    string
+		d    *time.Duration
+	}{
+		{name: "delay", s: h.Delay, d: &h.delay},
+		{name: "randomDelay", s: h.RandomDelay, d: &h.randomDelay},
+		{name: "timeout", s: h.Timeout, d: &h.timeout},
+		{name: "backoff initial", s: h.Backoff.Initial, d: &h.Backoff.initial},
+		{name: "backoff max", s: h.Backoff.Max, d: &h.Backoff.max},
+		{name: "maxRetryAfter", s: h.MaxRetryAfter, d: &h.maxRetryAfter},
+	} {
+		if d.s == "" {
+			continue
+		}
+		var err error
+		if *d.d, err = time.ParseDuration(d.s); err != nil {
+			return fmt.Errorf("parsing %v duration for host %q: %w", d.name, hostGlob, err)
+		}
+	}
+	return nil
+}
+
+// hostPolicy is a HostConfig with defaults applied.
+type hostPolicy struct {
+	glob          glob.Glob
+	hostGlob      string
+	parallelism   int
+	delay         time.Duration
+	randomDelay   time.Duration
+	timeout       time.Duration
+	maxRetries    int
+	backoff       BackoffConfig
+	maxRetryAfter time.Duration
+	headFirst     bool
+}
+
+// hostPolicies match hosts to their policies. The longest matching glob wins, with global policy for others. Parallelism
+// is limited per glob, not per host.
+type hostPolicies struct {
+	policies []hostPolicy
+	global   hostPolicy
+}
+
+func newHostPolicies(config Config) *hostPolicies {
+	p := &hostPolicies{global: hostPolicy{
+		glob:          glob.MustCompile("*"),
+		hostGlob:      "*",
+		parallelism:   defaultParallelism,
+		randomDelay:   config.randomDelay,
+		timeout:       defaultTimeout,
+		maxRetries:    defaultMaxRetries,
+		backoff:       BackoffConfig{initial: defaultBackoffInitial, max: defaultBackoffMax},
+		maxRetryAfter: defaultMaxRetryAfter,
+		headFirst:     !config.DisableHeadFirst,
+	}}
+	if config.Parallelism > 0 {
+		p.global.parallelism = config.Parallelism
+	}
+	if config.timeout > 0 {
+		p.global.timeout = config.timeout
+	}
+	if h, ok := config.Hosts["*"]; ok {
+		p.global = h.apply(p.global)
+	}
+
+	for hostGlob, h := range config.Hosts {
+		if hostGlob == "*" {
+			continue
+		}
+		policy := h.apply(p.global)
+		policy.glob = glob.MustCompile(hostGlob)
+		policy.hostGlob = hostGlob
+		p.policies = append(p.policies, policy)
+	}
+	sort.Slice(p.policies, func(i, j int) bool {
+		if len(p.policies[i].hostGlob) != len(p.policies[j].hostGlob) {
+			return len(p.policies[i].hostGlob) > len(p.policies[j].hostGlob)
+		}
+		return p.policies[i].hostGlob < p.policies[j].hostGlob
+	})
+	return p
+}
+
+func (h HostConfig) apply(p hostPolicy) hostPolicy {
+	if h.Parallelism > 0 {
+		p.parallelism = h.Parallelism
+	}
+	if h.Delay != "" {
+		p.delay = h.delay
+	}
+	if h.RandomDelay != "" {
+		p.randomDelay = h.randomDelay
+	}
+	if h.Timeout != "" {
+		p.timeout = h.timeout
+	}
+	if h.MaxRetries != 0 {
+		p.maxRetries = h.MaxRetries
+	}
+	if p.maxRetries < 0 {
+		p.maxRetries = 0
+	}
+	if h.Backoff.Initial != "" {
+		p.backoff.initial = h.Backoff.initial
+	}
+	if h.Backoff.Max != "" {
+		p.backoff.max = h.Backoff.max
+	}
+	if h.MaxRetryAfter != "" {
+		p.maxRetryAfter = h.maxRetryAfter
+	}
+	if h.DisableHeadFirst {
+		p.headFirst = false
+	}
+	return p
+}
+
+// forHost returns policy for given host (with port, if any).
+func (p *hostPolicies) forHost(host string) hostPolicy {
+	for _, policy := range p.policies {
+		if policy.glob.Match(host) {
+			return policy
+		}
+	}
+	return p.global
+}
+
+// limitRules returns colly limit rules in order of matching.
+func (p *hostPolicies) limitRules() []*colly.LimitRule {
+	rules := make([]*colly.LimitRule, 0, len(p.policies)+1)
+	for _, policy := range append(p.policies, p.global) {
+		rules = append(rules, &colly.LimitRule{
+			DomainGlob:  policy.hostGlob,
+			Parallelism: policy.parallelism,
+			Delay:       policy.delay,
+			RandomDelay: policy.randomDelay,
+		})
+	}
+	return rules
+}
+
+// maxTimeout returns the longest timeout of all policies.
+func (p *hostPolicies) maxTimeout() time.Duration {
+	max := p.global.timeout
+	for _, policy := range p.policies {
+		if policy.timeout > max {
+			max = policy.timeout
+		}
+	}
+	return max
+}
+
+// backoffFor returns duration to wait before given retry (starting from 0). Retry-After header, if any, takes precedence.
+// Error is returned if Retry-After is longer than maxRetryAfter, so request should not be retried.
+func (p hostPolicy) backoffFor(retry int, headers *http.Header, r *lockedRand) (time.Duration, error) {
+	if headers != nil {
+		if d, ok := parseRetryAfter(headers.Get("Retry-After"), time.Now()); ok {
+			if d > p.maxRetryAfter {
+				return 0, fmt.Errorf("retry-after %v too long, longer than maxRetryAfter %v", d, p.maxRetryAfter)
+			}
+			return d, nil
+		}
+	}
+
+	d := p.backoff.initial
+	for i := 0; i < retry && d < p.backoff.max; i++ {
+		d *= 2
+	}
+	if d > p.backoff.max {
+		d = p.backoff.max
+	}
+	if d <= 0 {
+		return 0, nil
+	}
+	// Jitter between half and full backoff, so retries of many links don't hit the host at once.
+	return d/2 + r.duration(d/2+1), nil
+}
+
+// parseRetryAfter parses Retry-After header value, which is either number of seconds or HTTP-date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// lockedRand is rand.Rand safe for concurrent use.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// duration returns random duration in [0, n).
+func (r *lockedRand) duration(n time.Duration) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Duration(r.r.Int63n(int64(n)))
+}
+
+// newTransport returns transport for all requests of validator.
+func newTransport(config Config) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: 5 * time.Second,
+		DialContext: (&net.Dialer{
+			Timeout:   100 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	}
+	if config.HostMaxConns != nil {
+		transport.MaxConnsPerHost = *config.HostMaxConns
+	}
+	return transport
+}
+
+// newAPIClient returns client for API requests e.g. to GitHub or GitLab API, with the same host timeouts as checked
+// links. Headers of validator are set by the caller.
+func newAPIClient(hosts *hostPolicies, transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: &timeoutTransport{policies: hosts, next: transport},
+		// Timeouts are applied per host by transport, so client timeout is only the upper bound.
+		Timeout: hosts.maxTimeout(),
+	}
+}
+
+// timeoutTransport applies per host request timeouts.
+type timeoutTransport struct {
+	policies *hostPolicies
+	next     http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.policies.forHost(req.URL.Host).timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Timeout covers reading the body as well.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tcase := range []struct {
+		value string
+		exp   time.Duration
+		ok    bool
+	}{
+		{value: ""},
+		{value: "invalid"},
+		{value: "-1"},
+		{value: "0", ok: true},
+		{value: "120", exp: 2 * time.Minute, ok: true},
+		{value: "Fri, 01 Jan 2021 00:00:30 GMT", exp: 30 * time.Second, ok: true},
+		{value: "Thu, 31 Dec 2020 00:00:00 GMT", ok: true},
+	} {
+		t.Run(tcase.value, func(t *testing.T) {
+			d, ok := parseRetryAfter(tcase.value, now)
+			testutil.Equals(t, tcase.ok, ok)
+			testutil.Equals(t, tcase.exp, d)
+		})
+	}
+}
+
+func TestHostPolicies(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`version: 1
+timeout: 20s
+parallelism: 10
+hosts:
+  '*':
+    maxRetries: 2
+  '*github.com':
+    parallelism: 2
+    backoff:
+      initial: 2s
+      max: 5s
+  'docs.github.com':
+    timeout: 1m
+    maxRetries: -1
+    maxRetryAfter: 5m
+    disableHeadFirst: true
+`))
+	testutil.Ok(t, err)
+	p := newHostPolicies(cfg)
+
+	testutil.Equals(t, time.Minute, p.maxTimeout())
+
+	docs := p.forHost("docs.github.com")
+	testutil.Equals(t, "docs.github.com", docs.hostGlob)
+	testutil.Equals(t, 10, docs.parallelism)
+	testutil.Equals(t, time.Minute, docs.timeout)
+	testutil.Equals(t, 0, docs.maxRetries)
+	testutil.Equals(t, false, docs.headFirst)
+
+	gh := p.forHost("api.github.com")
+	testutil.Equals(t, "*github.com", gh.hostGlob)
+	testutil.Equals(t, 2, gh.parallelism)
+	testutil.Equals(t, 20*time.Second, gh.timeout)
+	testutil.Equals(t, 2, gh.maxRetries)
+	testutil.Equals(t, true, gh.headFirst)
+
+	other := p.forHost("bwplotka.dev")
+	testutil.Equals(t, "*", other.hostGlob)
+	testutil.Equals(t, 2, other.maxRetries)
+
+	r := newLockedRand()
+	for retry, exp := range []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		d, err := gh.backoffFor(retry, nil, r)
+		testutil.Ok(t, err)
+		testutil.Assert(t, d >= exp/2 && d <= exp, "retry %v: unexpected backoff %v", retry, d)
+	}
+	// Retry-After is honored exactly, even if longer than max backoff, up to maxRetryAfter.
+	for _, retryAfter := range []string{"3", "60"} {
+		d, err := gh.backoffFor(0, &http.Header{"Retry-After": []string{retryAfter}}, r)
+		testutil.Ok(t, err)
+		exp, _ := strconv.Atoi(retryAfter)
+		testutil.Equals(t, time.Duration(exp)*time.Second, d)
+	}
+	_, err = gh.backoffFor(0, &http.Header{"Retry-After": []string{"61"}}, r)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "retry-after 1m1s too long, longer than maxRetryAfter 1m0s", err.Error())
+
+	d, err := docs.backoffFor(0, &http.Header{"Retry-After": []string{"120"}}, r)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2*time.Minute, d)
+
+	_, err = ParseConfig([]byte("version: 1\nhosts:\n  'github.com':\n    backoff:\n      max: yolo\n"))
+	testutil.NotOk(t, err)
+	_, err = ParseConfig([]byte("version: 1\nhosts:\n  'github.com':\n    parallelism: -1\n"))
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.HasSuffix(err.Error(), `parsing parallelism for host "github.com", has to be >= 0`), err.Error())
+}
@@ -0,0 +1,202 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+)
+
+const (
+	LinkTypeInternal = "internal"
+	LinkTypeExternal = "external"
+)
+
+// Link is a link found in markdown file.
+type Link struct {
+	// Filepath is path of file with the link, relative to anchor dir.
+	Filepath string `json:"file"`
+	// LineNumbers are comma separated lines with the same destination.
+	LineNumbers string `json:"line"`
+	Text        string `json:"text"`
+	Destination string `json:"destination"`
+	// Type is LinkTypeInternal for links to local files and LinkTypeExternal for others e.g. remote pages or emails.
+	Type string `json:"type"`
+	// Status is empty if link was not validated.
+	Status LinkStatus `json:"status,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type linkKey struct {
+	filepath, dest, lineNumbers string
+}
+
+// LinkInventory is mdformatter.LinkTransformer which collects all links with their validation results, if validator
+// passes them to SetResult, without changing links.
+type LinkInventory struct {
+	anchorDir string
+
+	mu    sync.Mutex
+	links []Link
+	// byKey are indexes of links by absolute path of file, destination and line numbers.
+	byKey map[linkKey][]int
+	// targets are absolute paths of linked local files, by index of link.
+	targets map[int]string
+}
+
+// NewLinkInventory returns LinkInventory resolving links absolute to anchorDir.
+func NewLinkInventory(anchorDir string) *LinkInventory {
+	return &LinkInventory{anchorDir: anchorDir, byKey: map[linkKey][]int{}, targets: map[int]string{}}
+}
+
+func (i *LinkInventory) TransformDestination(ctx mdformatter.SourceContext, destination []byte) ([]byte, error) {
+	dest := string(destination)
+	rel, err := filepath.Rel(i.anchorDir, ctx.Filepath)
+	if err != nil {
+		return nil, fmt.Errorf("find relative path: %w", err)
+	}
+	l := Link{
+		Filepath:    filepath.ToSlash(rel),
+		LineNumbers: ctx.LineNumbers,
+		Text:        ctx.LinkText,
+		Destination: dest,
+		Type:        LinkTypeInternal,
+	}
+	if remoteLinkPrefixRe.MatchString(dest) || strings.Contains(dest, ":") {
+		l.Type = LinkTypeExternal
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	idx := len(i.links)
+	i.links = append(i.links, l)
+	k := linkKey{filepath: ctx.Filepath, dest: dest, lineNumbers: ctx.LineNumbers}
+	i.byKey[k] = append(i.byKey[k], idx)
+	if l.Type == LinkTypeInternal {
+		target, _ := splitFragment(absLocalLink(i.anchorDir, ctx.Filepath, strings.SplitN(dest, "?", 2)[0]))
+		i.targets[idx] = target
+	}
+	return destination, nil
+}
+
+func (i *LinkInventory) Close(mdformatter.SourceContext) error { return nil }
+
+// SetResult sets validation result of links. It can be passed to validator using WithResultFn.
+func (i *LinkInventory) SetResult(r LinkResult) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, idx := range i.byKey[linkKey{filepath: r.Filepath, dest: r.Destination, lineNumbers: r.LineNumbers}] {
+		i.links[idx].Status = r.Status
+		if r.Err != nil {
+			i.links[idx].Error = r.Err.Error()
+		}
+	}
+}
+
+// Links returns collected links in order they were found.
+func (i *LinkInventory) Links() []Link {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]Link(nil), i.links...)
+}
+
+// WriteJSON writes collected links as JSON array.
+func (i *LinkInventory) WriteJSON(w io.Writer) error {
+	links := i.Links()
+	if links == nil {
+		links = []Link{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(links)
+}
+
+// WriteCSV writes collected links as CSV with header.
+func (i *LinkInventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"file", "line", "text", "destination", "type", "status", "error"}); err != nil {
+		return err
+	}
+	for _, l := range i.Links() {
+		if err := cw.Write([]string{l.Filepath, l.LineNumbers, l.Text, l.Destination, l.Type, string(l.Status), l.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDOT writes graph of links in Graphviz DOT format. Internal links point to linked files relative to anchor dir,
+// external links point to their scheme and host (dashed), so most linked domains stand out. Edges of failed links are
+// red and labeled with number of links, if more than one.
+func (i *LinkInventory) WriteDOT(w io.Writer) error {
+	type edge struct {
+		from, to string
+		external bool
+	}
+	var edges []edge
+	counts := map[edge]int{}
+	failed := map[edge]bool{}
+
+	i.mu.Lock()
+	for idx, l := range i.links {
+		e := edge{from: l.Filepath, to: l.Destination, external: l.Type == LinkTypeExternal}
+		if e.external {
+			if u, err := url.Parse(l.Destination); err == nil && u.Host != "" {
+				e.to = u.Scheme + "://" + u.Host
+			}
+		} else if rel, err := filepath.Rel(i.anchorDir, i.targets[idx]); err == nil {
+			e.to = filepath.ToSlash(rel)
+		}
+		if _, ok := counts[e]; !ok {
+			edges = append(edges, e)
+		}
+		counts[e]++
+		if l.Status == LinkStatusInvalid || l.Status == LinkStatusWarning {
+			failed[e] = true
+		}
+	}
+	i.mu.Unlock()
+
+	sort.SliceStable(edges, func(a, b int) bool {
+		if edges[a].from != edges[b].from {
+			return edges[a].from < edges[b].from
+		}
+		return edges[a].to < edges[b].to
+	})
+	if _, err := fmt.Fprintln(w, "digraph links {"); err != nil {
+		return err
+	}
+	for _, e := range edges {
+		var attrs []string
+		if e.external {
+			attrs = append(attrs, "style=dashed")
+		}
+		if failed[e] {
+			attrs = append(attrs, "color=red")
+		}
+		if counts[e] > 1 {
+			attrs = append(attrs, "label="+strconv.Itoa(counts[e]))
+		}
+		line := fmt.Sprintf("  %v -> %v", strconv.Quote(e.from), strconv.Quote(e.to))
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		if _, err := fmt.Fprintln(w, line+";"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -37,17 +37,30 @@ var remoteLinkPrefixRe = regexp.MustCompile(`^http[s]?://`)
 type LookupError error
 
 var (
-	FileNotFoundErr = LookupError(errors.New("file not found"))
-	IDNotFoundErr   = LookupError(errors.New("file exists, but does not have such id"))
+	FileNotFoundErr     = LookupError(errors.New("file not found"))
+	IDNotFoundErr       = LookupError(errors.New("file exists, but does not have such id"))
+	RemoteIDNotFoundErr = LookupError(errors.New("page exists, but does not have such id"))
 )
 
+// gitHubUserContentPrefix is prefix GitHub adds to ids of headers in rendered markdown, while links omit it.
+const gitHubUserContentPrefix = "user-content-"
+
+// unverifiableFragmentRe matches fragments which are not element ids, e.g. GitHub line anchors or text fragments.
+var unverifiableFragmentRe = regexp.MustCompile(`^(L\d+(C\d+)?(-L\d+(C\d+)?)?|:~:.*)$`)
+
 type linktransformerMetrics struct {
 	localLinksChecked     prometheus.Counter
 	remoteLinksChecked    prometheus.Counter
 	roundTripVisitedLinks prometheus.Counter
 	roundTripCachedLinks  prometheus.Counter
 	githubSkippedLinks    prometheus.Counter
+	githubCachedLinks     prometheus.Counter
+	githubGraphQLQueries  prometheus.Counter
+	gitlabSkippedLinks    prometheus.Counter
+	warnedLinks           prometheus.Counter
+	giteaSkippedLinks     prometheus.Counter
 	ignoreSkippedLinks    prometheus.Counter
+	offlineSkippedLinks   prometheus.Counter
 
 	collyRequests         *prometheus.CounterVec
 	collyPerDomainLatency *prometheus.HistogramVec
@@ -76,11 +89,37 @@ func newLinktransformerMetrics(reg *prometheus.Registry) *linktransformerMetrics
 		Name: "mdox_github_skipped_links_total",
 		Help: "The total number of links which were github checked",
 	})
+	l.githubCachedLinks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_github_cached_links_total",
+		Help: "The total number of github links which were precisely checked before and cached",
+	})
+	l.githubGraphQLQueries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_github_graphql_queries_total",
+		Help: "The total number of GitHub GraphQL queries made to check github links precisely",
+	})
+	l.gitlabSkippedLinks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_gitlab_skipped_links_total",
+		Help: "The total number of links which were gitlab checked",
+	})
+	l.giteaSkippedLinks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_gitea_skipped_links_total",
+		Help: "The total number of links which were gitea checked",
+	})
 	l.ignoreSkippedLinks = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "mdox_ignore_skipped_links_total",
 		Help: "The total number of links which were ignore checked",
 	})
 
+	l.warnedLinks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_warned_links_total",
+		Help: "The total number of failed links with warn severity, which did not fail validation",
+	})
+
+	l.offlineSkippedLinks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mdox_offline_skipped_links_total",
+		Help: "The total number of remote links which were not checked in local-only or cache-only mode",
+	})
+
 	l.collyRequests = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "mdox_colly_requests_total"},
 		[]string{},
@@ -91,14 +130,17 @@ func newLinktransformerMetrics(reg *prometheus.Registry) *linktransformerMetrics
 	)
 
 	if reg != nil {
-		reg.MustRegister(l.localLinksChecked, l.remoteLinksChecked, l.roundTripVisitedLinks, l.roundTripCachedLinks, l.githubSkippedLinks, l.ignoreSkippedLinks, l.collyRequests, l.collyPerDomainLatency)
+		reg.MustRegister(l.localLinksChecked, l.remoteLinksChecked, l.roundTripVisitedLinks, l.roundTripCachedLinks, l.githubSkippedLinks, l.githubCachedLinks, l.githubGraphQLQueries, l.gitlabSkippedLinks, l.giteaSkippedLinks, l.ignoreSkippedLinks, l.warnedLinks, l.offlineSkippedLinks, l.collyRequests, l.collyPerDomainLatency)
 	}
 	return l
 }
 
 const (
-	originalURLKey     = "originalURLKey"
-	numberOfRetriesKey = "retryKey"
+	originalURLKey       = "originalURLKey"
+	numberOfRetriesKey   = "retryKey"
+	needBodyKey          = "needBodyKey"
+	acceptStatusCodesKey = "acceptStatusCodesKey"
+	revalidateKey        = "revalidateKey"
 )
 
 type chain struct {
@@ -128,7 +170,7 @@ func (l *chain) Close(ctx mdformatter.SourceContext) error {
 }
 
 type localizer struct {
-	address   *regexp.Regexp
+	targets   []localizeTarget
 	anchorDir string
 
 	localLinksByFile localLinksCache
@@ -138,34 +180,37 @@ type localizer struct {
 
 // NewLocalizer returns mdformatter.LinkTransformer that transforms links that matches address via given regexp to local markdown file path (if exists).
 func NewLocalizer(logger log.Logger, address *regexp.Regexp, anchorDir string) mdformatter.LinkTransformer {
-	return &localizer{logger: logger, address: address, anchorDir: anchorDir, localLinksByFile: map[string]*[]string{}}
+	return &localizer{logger: logger, targets: []localizeTarget{{address: address, dir: anchorDir}}, anchorDir: anchorDir, localLinksByFile: map[string]*[]string{}}
 }
 
 func (l *localizer) TransformDestination(ctx mdformatter.SourceContext, destination []byte) (_ []byte, err error) {
 	matches := remoteLinkPrefixRe.FindAllIndex(destination, 1)
 	if matches != nil {
 		// URLs. Remove http/https prefix.
-		newDest := string(destination[matches[0][1]:])
-		// NOTE: We don't check if passed regexp does not make sense (it's empty string etc).
-		matches = l.address.FindAllStringIndex(newDest, 1)
-		if matches == nil {
-			return destination, nil
-		}
+		address := string(destination[matches[0][1]:])
+		for _, t := range l.targets {
+			// NOTE: We don't check if passed regexp does not make sense (it's empty string etc).
+			matches = t.address.FindAllStringIndex(address, 1)
+			if matches == nil {
+				continue
+			}
 
-		// Remove matched address.
-		newDest = filepath.Join(l.anchorDir, newDest[matches[0][1]:])
-		if err := l.localLinksByFile.Lookup(newDest); err != nil {
-			level.Debug(l.logger).Log("msg", "attempted localization failed, no such local link; skipping", "err", err)
-			return destination, nil
+			// Remove matched address.
+			newDest := filepath.Join(t.dir, address[matches[0][1]:])
+			if err := l.localLinksByFile.Lookup(l.anchorDir, newDest); err != nil {
+				level.Debug(l.logger).Log("msg", "attempted localization failed, no such local link; skipping", "err", err)
+				continue
+			}
+			// NOTE: This assumes GetAnchorDir was used, so we validated if docPath is in the path of anchorDir.
+			return absLinkToRelLink(newDest, ctx.Filepath)
 		}
-		// NOTE: This assumes GetAnchorDir was used, so we validated if docPath is in the path of anchorDir.
-		return absLinkToRelLink(newDest, ctx.Filepath)
+		return destination, nil
 	}
 
 	// Relative or absolute path.
 	newDest := absLocalLink(l.anchorDir, ctx.Filepath, string(destination))
 
-	if err := l.localLinksByFile.Lookup(newDest); err != nil {
+	if err := l.localLinksByFile.Lookup(l.anchorDir, newDest); err != nil {
 		level.Debug(l.logger).Log("msg", "attempted localization failed, no such local link; skipping", "err", err)
 		return destination, nil
 	}
@@ -180,17 +225,36 @@ type validator struct {
 	anchorDir      string
 	validateConfig Config
 
-	localLinks  localLinksCache
-	rMu         sync.RWMutex
-	remoteLinks map[string]error
-	c           *colly.Collector
-	storage     *cache.SQLite3Storage
+	localLinks localLinksCache
+	// localLineCounts holds number of lines of local files referenced with line anchors.
+	localLineCounts map[string]int
+	rMu             sync.RWMutex
+	remoteLinks     map[string]error
+	// remoteIDs holds ids and names of elements of fetched HTML pages. Non HTML pages have no entry.
+	remoteIDs map[string]map[string]struct{}
+	// remoteVisits holds pages requested so far and whether their body was requested too.
+	remoteVisits map[string]bool
+	// remoteRedirects holds final URL of pages redirected only with permanent (301, 308) redirects.
+	remoteRedirects map[string]string
+	fixRedirects    bool
+	mode            Mode
+	failOn          Severity
+	resultFn        func(LinkResult)
+	warnings        *Warnings
+	c               *colly.Collector
+	storage         cache.Storage
 
 	futureMu    sync.Mutex
 	destFutures map[futureKey]*futureResult
 
+	hosts    *hostPolicies
+	rand     *lockedRand
+	redactor *redactor
+
 	l           *linktransformerMetrics
 	transportFn func(url string) http.RoundTripper
+	// apiClient is used for API requests e.g. to GitHub GraphQL API.
+	apiClient *http.Client
 }
 
 type futureKey struct {
@@ -201,154 +265,401 @@ type futureResult struct {
 	// function giving result, promised after colly.Wait.
 	resultFn func() error
 	cases    int
+	// unverified is true if link could not be checked without network access.
+	unverified bool
+	// skipped is true if link is not checked on purpose e.g. ignored or remote in ModeLocalOnly.
+	skipped  bool
+	severity Severity
+}
+
+// Mode of link validation.
+type Mode string
+
+const (
+	// ModeFull checks local and remote links.
+	ModeFull Mode = "full"
+	// ModeLocalOnly checks only local links and anchors, without network access.
+	ModeLocalOnly Mode = "local-only"
+	// ModeCacheOnly checks local links and takes results of remote links from cache, without network access. Remote
+	// links without cached result are reported as unverified, but do not fail.
+	ModeCacheOnly Mode = "cache-only"
+)
+
+type validatorOptions struct {
+	fixRedirects bool
+	mode         Mode
+	failOn       Severity
+	resultFn     func(LinkResult)
+	warnings     *Warnings
+}
+
+// ValidatorOption is a functional option for NewValidator.
+type ValidatorOption func(*validatorOptions)
+
+// WithFixRedirects makes validator rewrite links which are permanently redirected to their final URL. Since links are
+// checked asynchronously, redirect has to be already known when link is transformed, so all files should be validated
+// first (e.g. using mdformatter.IsFormatted) and then formatted using the same validator.
+// Cache is not used in this mode, so all links are checked.
+func WithFixRedirects() ValidatorOption {
+	return func(o *validatorOptions) {
+		o.fixRedirects = true
+	}
+}
+
+// WithMode sets validation mode. ModeFull is used by default. Emails are checked only syntactically in other modes.
+func WithMode(m Mode) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.mode = m
+	}
+}
+
+// WithFailOn sets the lowest severity of failed links which fails validation. SeverityError is used by default, so
+// failed links of validators with SeverityWarn are only reported. With SeverityWarn all failed links fail validation.
+func WithFailOn(s Severity) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.failOn = s
+	}
+}
+
+// LinkStatus is validation status of link.
+type LinkStatus string
+
+const (
+	LinkStatusValid LinkStatus = "valid"
+	// LinkStatusInvalid is status of failed link, which fails validation.
+	LinkStatusInvalid LinkStatus = "invalid"
+	// LinkStatusWarning is status of failed link, which is only reported e.g. of validator with SeverityWarn.
+	LinkStatusWarning LinkStatus = "warning"
+	// LinkStatusUnverified is status of remote link without cached result in ModeCacheOnly.
+	LinkStatusUnverified LinkStatus = "unverified"
+	// LinkStatusSkipped is status of link which is not checked e.g. ignored or remote in ModeLocalOnly.
+	LinkStatusSkipped LinkStatus = "skipped"
+)
+
+// LinkResult is validation result of link. Links with the same destination in a file have the same result.
+type LinkResult struct {
+	Filepath    string
+	Destination string
+	LineNumbers string
+	Status      LinkStatus
+	// Err is set for failed links.
+	Err error
+}
+
+// WithResultFn makes validator pass result of every validated link to given function, when file is closed.
+func WithResultFn(fn func(LinkResult)) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.resultFn = fn
+	}
+}
+
+// WithWarnings makes validator collect failed links with warn severity, which did not fail validation, of all files in
+// given Warnings, instead of logging them per file.
+func WithWarnings(w *Warnings) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.warnings = w
+	}
+}
+
+// Warnings collects failed links with warn severity across files, so they can be reported together in one summary.
+type Warnings struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (w *Warnings) add(errs ...error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = append(w.errs, errs...)
+}
+
+// Len returns number of collected warnings.
+func (w *Warnings) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.errs)
+}
+
+// Err returns collected warnings as multi error, or nil if there are none.
+func (w *Warnings) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	merr := merrors.New()
+	merr.Add(w.errs...)
+	return merr.Err()
+}
+
+// Reset drops collected warnings e.g. if the same files are validated again.
+func (w *Warnings) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = nil
+}
+
+// Log logs summary of collected warnings with their count, if there are any.
+func (w *Warnings) Log(logger log.Logger) {
+	if err := w.Err(); err != nil {
+		level.Warn(logger).Log("msg", fmt.Sprintf("%v links with warn severity failed", w.Len()), "err", err)
+	}
 }
 
 // NewValidator returns mdformatter.LinkTransformer that crawls all links.
 // TODO(bwplotka): Add optimization and debug modes - this is the main source of latency and pain.
-func NewValidator(ctx context.Context, logger log.Logger, linksValidateConfig []byte, anchorDir string, storage *cache.SQLite3Storage, reg *prometheus.Registry) (mdformatter.LinkTransformer, error) {
+func NewValidator(ctx context.Context, logger log.Logger, linksValidateConfig []byte, anchorDir string, storage cache.Storage, reg *prometheus.Registry, opts ...ValidatorOption) (mdformatter.LinkTransformer, error) {
+	o := validatorOptions{mode: ModeFull, failOn: SeverityError}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.mode {
+	case ModeFull, ModeLocalOnly, ModeCacheOnly:
+	default:
+		return nil, fmt.Errorf("unsupported validation mode %q", o.mode)
+	}
+	switch o.failOn {
+	case SeverityError, SeverityWarn:
+	default:
+		return nil, fmt.Errorf("unsupported severity to fail on %q", o.failOn)
+	}
+	if o.fixRedirects && o.mode != ModeFull {
+		return nil, fmt.Errorf("fixing redirects requires %v mode", ModeFull)
+	}
+
 	var err error
 	config := Config{}
 	if string(linksValidateConfig) != "" {
-		config, err = ParseConfig(linksValidateConfig)
+		config, err = parseConfig(linksValidateConfig, o.mode != ModeFull)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	linktransformerMetrics := newLinktransformerMetrics(reg)
-	transport := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 30 * time.Second,
-		ExpectContinueTimeout: 5 * time.Second,
-		DialContext: (&net.Dialer{
-			Timeout:   100 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-	}
-	if config.HostMaxConns != nil {
-		transport.MaxConnsPerHost = *config.HostMaxConns
-	}
+	transport := newTransport(config)
+	hosts := newHostPolicies(config)
+	redactor := newRedactor(config)
 	v := &validator{
-		logger:         logger,
-		anchorDir:      anchorDir,
-		validateConfig: config,
-		localLinks:     map[string]*[]string{},
-		remoteLinks:    map[string]error{},
-		c:              colly.NewCollector(colly.Async(), colly.StdlibContext(ctx)),
-		storage:        nil,
-		destFutures:    map[futureKey]*futureResult{},
-		l:              linktransformerMetrics,
+		logger:          redactor.logger(logger),
+		anchorDir:       anchorDir,
+		validateConfig:  config,
+		localLinks:      map[string]*[]string{},
+		localLineCounts: map[string]int{},
+		remoteLinks:     map[string]error{},
+		remoteIDs:       map[string]map[string]struct{}{},
+		remoteVisits:    map[string]bool{},
+		remoteRedirects: map[string]string{},
+		fixRedirects:    o.fixRedirects,
+		mode:            o.mode,
+		failOn:          o.failOn,
+		resultFn:        o.resultFn,
+		warnings:        o.warnings,
+		c:               colly.NewCollector(colly.Async(), colly.StdlibContext(ctx)),
+		storage:         nil,
+		destFutures:     map[futureKey]*futureResult{},
+		hosts:           hosts,
+		rand:            newLockedRand(),
+		redactor:        redactor,
+		l:               linktransformerMetrics,
+		apiClient:       newAPIClient(hosts, transport),
 		transportFn: func(u string) http.RoundTripper {
 			parsed, err := url.Parse(u)
 			if err != nil {
 				panic(err)
 			}
+			var rt http.RoundTripper = &timeoutTransport{policies: hosts, next: transport}
+			if headers := config.headersForURL(u); headers != nil {
+				rt = &headerTransport{headers: headers, next: rt}
+			}
 			return promhttp.InstrumentRoundTripperCounter(
 				linktransformerMetrics.collyRequests,
 				promhttp.InstrumentRoundTripperDuration(
 					linktransformerMetrics.collyPerDomainLatency.MustCurryWith(prometheus.Labels{"domain": parsed.Host}),
-					transport,
+					rt,
 				),
 			)
 		},
 	}
+	// Transport is chosen per request, as requests are made concurrently.
+	v.c.WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return v.transportFn(req.URL.String()).RoundTrip(req)
+	}))
 
-	// Set very soft limits.
-	// E.g GitHub has 50-5000 https://docs.github.com/en/free-pro-team@latest/rest/reference/rate-limit limit depending
-	// on API (only search is below 100).
-	if config.Timeout != "" {
-		v.c.SetRequestTimeout(config.timeout)
-	}
+	// Timeouts are applied per host by transport, so client timeout is only the upper bound.
+	v.c.SetRequestTimeout(hosts.maxTimeout())
 
 	if v.validateConfig.Cache.IsSet() && storage != nil {
 		v.storage = storage
-		if err = v.storage.Init(v.validateConfig.Cache.Validity, v.validateConfig.Cache.Jitter); err != nil {
+		if err = v.storage.Init(v.validateConfig.Cache); err != nil {
 			return nil, err
 		}
 	}
-
-	limitRule := &colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: 100,
-	}
-	if config.Parallelism > 0 {
-		limitRule.Parallelism = config.Parallelism
+	if v.mode == ModeCacheOnly && v.storage == nil {
+		return nil, fmt.Errorf("%v mode requires cache to be configured", ModeCacheOnly)
 	}
-	if config.RandomDelay != "" {
-		limitRule.RandomDelay = config.randomDelay
-	}
-	if err := v.c.Limit(limitRule); err != nil {
+
+	// Set very soft limits by default.
+	// E.g GitHub has 50-5000 https://docs.github.com/en/free-pro-team@latest/rest/reference/rate-limit limit depending
+	// on API (only search is below 100).
+	if err := v.c.Limits(hosts.limitRules()); err != nil {
 		return nil, err
 	}
+	// Pages are deduplicated by validator, as the same page might be requested with different methods.
+	v.c.AllowURLRevisit = true
+	v.c.SetRedirectHandler(func(req *http.Request, via []*http.Request) error {
+		// Honor Go default of maximum of 10 redirects.
+		if len(via) >= 10 {
+			return http.ErrUseLastResponse
+		}
+		if req.URL.Host != via[len(via)-1].URL.Host {
+			req.Header.Del("Authorization")
+		}
+
+		permanent := true
+		for _, r := range append(via[1:], req) {
+			if r.Response == nil || (r.Response.StatusCode != http.StatusMovedPermanently && r.Response.StatusCode != http.StatusPermanentRedirect) {
+				permanent = false
+				break
+			}
+		}
+		v.rMu.Lock()
+		defer v.rMu.Unlock()
+		if !permanent {
+			delete(v.remoteRedirects, via[0].URL.String())
+			return nil
+		}
+		v.remoteRedirects[via[0].URL.String()] = req.URL.String()
+		return nil
+	})
 	v.c.OnRequest(func(request *colly.Request) {
 		v.rMu.Lock()
 		defer v.rMu.Unlock()
 		request.Ctx.Put(originalURLKey, request.URL.String())
 	})
-	v.c.OnScraped(func(response *colly.Response) {
+	v.c.OnResponseHeaders(func(response *colly.Response) {
+		if response.Request.Method != http.MethodGet || response.Ctx.GetAny(needBodyKey) != nil || response.StatusCode >= 203 {
+			return
+		}
+		// Page exists and we don't need its content, so don't download it.
 		v.rMu.Lock()
 		defer v.rMu.Unlock()
-		if v.storage != nil {
-			if err := v.storage.CacheURL(response.Ctx.Get(originalURLKey)); err != nil {
-				v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link not saved to cache %v: %w", response.Ctx.Get(originalURLKey), err)
-			}
+		v.markValid(response.Ctx.Get(originalURLKey), response.StatusCode, response.Headers)
+		response.Request.Abort()
+	})
+	v.c.OnResponse(func(response *colly.Response) {
+		if response.Request.Method != http.MethodGet || !strings.Contains(strings.ToLower(response.Headers.Get("Content-Type")), "html") {
+			return
 		}
-		v.remoteLinks[response.Ctx.Get(originalURLKey)] = nil
+		v.rMu.Lock()
+		defer v.rMu.Unlock()
+		v.remoteIDs[response.Ctx.Get(originalURLKey)] = map[string]struct{}{}
 	})
-	v.c.OnError(func(response *colly.Response, err error) {
+	v.c.OnHTML("[id], [name]", func(e *colly.HTMLElement) {
 		v.rMu.Lock()
 		defer v.rMu.Unlock()
-		retriesStr := response.Ctx.Get(numberOfRetriesKey)
-		retries, _ := strconv.Atoi(retriesStr)
-		switch response.StatusCode {
-		case http.StatusTooManyRequests:
-			if retries > 0 {
-				break
-			}
-			var retryAfterSeconds int
-			// Retry calls same methods as Visit and makes request with same options.
-			// So retryKey is incremented here if onError is called again after Retry. By default retries once.
-			response.Ctx.Put(numberOfRetriesKey, strconv.Itoa(retries+1))
-			retryAfterSeconds, convErr := strconv.Atoi(response.Headers.Get("Retry-After"))
-			if convErr != nil {
-				retryAfterSeconds = 1
-			}
-			select {
-			case <-time.After(time.Duration(retryAfterSeconds) * time.Second):
-			case <-v.c.Context.Done():
-				return
-			}
-
-			if retryErr := response.Request.Retry(); retryErr != nil {
-				v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link retry %v: %w", response.Ctx.Get(originalURLKey), err)
-				break
-			}
-			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("%q rate limited even after retry; status code %v: %w", response.Request.URL.String(), response.StatusCode, err)
-		// 0 StatusCode means error on call side.
-		case http.StatusMovedPermanently, http.StatusTemporaryRedirect, http.StatusServiceUnavailable, 0:
-			if retries > 0 {
-				break
+		ids, ok := v.remoteIDs[e.Request.Ctx.Get(originalURLKey)]
+		if !ok {
+			return
+		}
+		for _, id := range []string{e.Attr("id"), e.Attr("name")} {
+			if id != "" {
+				ids[id] = struct{}{}
 			}
-			response.Ctx.Put(numberOfRetriesKey, strconv.Itoa(retries+1))
+		}
+	})
+	v.c.OnScraped(func(response *colly.Response) {
+		v.rMu.Lock()
+		defer v.rMu.Unlock()
+		v.markValid(response.Ctx.Get(originalURLKey), response.StatusCode, response.Headers)
+	})
+	v.c.OnError(func(response *colly.Response, err error) {
+		wait, retry := v.onError(response, err)
+		if !retry {
+			return
+		}
 
-			if retryErr := response.Request.Retry(); retryErr != nil {
-				v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link retry %v: %w", response.Ctx.Get(originalURLKey), err)
-				break
-			}
-			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("%q not accessible even after retry; status code %v: %w", response.Request.URL.String(), response.StatusCode, err)
-		default:
-			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("%q not accessible; status code %v: %w", response.Request.URL.String(), response.StatusCode, err)
+		// Wait without holding the lock, so other responses can be processed.
+		select {
+		case <-time.After(wait):
+		case <-v.c.Context.Done():
+			v.rMu.Lock()
+			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link retry %v: %w", response.Ctx.Get(originalURLKey), v.c.Context.Err())
+			v.rMu.Unlock()
+			return
+		}
+		// Retry calls same methods as Visit and makes request with same options and context, so retries are counted.
+		if retryErr := response.Request.Retry(); retryErr != nil {
+			v.rMu.Lock()
+			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link retry %v: %w", response.Ctx.Get(originalURLKey), retryErr)
+			v.rMu.Unlock()
 		}
 	})
 	return v, nil
 }
 
+// onError records failed request and returns whether and after what time it should be retried, following host policy.
+func (v *validator) onError(response *colly.Response, err error) (wait time.Duration, retry bool) {
+	v.rMu.Lock()
+	defer v.rMu.Unlock()
+	if errors.Is(err, colly.ErrAbortedAfterHeaders) {
+		// Aborted on purpose, result is already known.
+		return 0, false
+	}
+	if e, ok := response.Ctx.GetAny(revalidateKey).(*cache.Entry); ok && response.StatusCode == http.StatusNotModified {
+		// Cached entry is still valid, refresh it.
+		v.remoteLinks[response.Ctx.Get(originalURLKey)] = nil
+		v.cacheResult(response.Ctx.Get(originalURLKey), e.Result)
+		return 0, false
+	}
+	if codes, ok := response.Ctx.GetAny(acceptStatusCodesKey).([]int); ok && containsInt(codes, response.StatusCode) {
+		level.Debug(v.logger).Log("msg", "accepting status code", "url", response.Ctx.Get(originalURLKey), "status", response.StatusCode)
+		// Not cached, as it does not prove that link exists.
+		v.remoteLinks[response.Ctx.Get(originalURLKey)] = nil
+		return 0, false
+	}
+	if response.Request.Method == http.MethodHead && (response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusForbidden) {
+		// Some servers don't support or forbid HEAD requests, fallback to GET.
+		if fallbackErr := v.c.Request(http.MethodGet, response.Request.URL.String(), nil, response.Ctx, nil); fallbackErr != nil {
+			v.remoteLinks[response.Ctx.Get(originalURLKey)] = fmt.Errorf("remote link GET fallback %v: %w", response.Ctx.Get(originalURLKey), fallbackErr)
+		}
+		return 0, false
+	}
+
+	switch response.StatusCode {
+	// 0 StatusCode means error on call side.
+	case http.StatusTooManyRequests, http.StatusTemporaryRedirect, http.StatusServiceUnavailable, 0:
+	default:
+		v.markInvalid(response.Ctx.Get(originalURLKey), response.StatusCode, err, fmt.Errorf("%q not accessible; status code %v: %w", response.Request.URL.String(), response.StatusCode, err))
+		return 0, false
+	}
+
+	policy := v.hosts.forHost(response.Request.URL.Host)
+	retries, _ := strconv.Atoi(response.Ctx.Get(numberOfRetriesKey))
+	var backoffErr error
+	if retries < policy.maxRetries {
+		var backoff time.Duration
+		if backoff, backoffErr = policy.backoffFor(retries, response.Headers, v.rand); backoffErr == nil {
+			response.Ctx.Put(numberOfRetriesKey, strconv.Itoa(retries+1))
+			return backoff, true
+		}
+	}
+
+	msg := "not accessible"
+	if response.StatusCode == http.StatusTooManyRequests {
+		msg = "rate limited"
+	}
+	switch {
+	case backoffErr != nil:
+		msg += fmt.Sprintf(", not retried as %v", backoffErr)
+	case retries == 1:
+		msg += " even after retry"
+	case retries > 1:
+		msg += fmt.Sprintf(" even after %d retries", retries)
+	}
+	v.markInvalid(response.Ctx.Get(originalURLKey), response.StatusCode, err, fmt.Errorf("%q %v; status code %v: %w", response.Request.URL.String(), msg, response.StatusCode, err))
+	return 0, false
+}
+
 // MustNewValidator returns mdformatter.LinkTransformer that crawls all links.
-func MustNewValidator(logger log.Logger, linksValidateConfig []byte, anchorDir string, storage *cache.SQLite3Storage) mdformatter.LinkTransformer {
+func MustNewValidator(logger log.Logger, linksValidateConfig []byte, anchorDir string, storage cache.Storage) mdformatter.LinkTransformer {
 	v, err := NewValidator(context.TODO(), logger, linksValidateConfig, anchorDir, storage, nil)
 	if err != nil {
 		panic(err)
@@ -358,24 +669,59 @@ func MustNewValidator(logger log.Logger, linksValidateConfig []byte, anchorDir s
 
 func (v *validator) TransformDestination(ctx mdformatter.SourceContext, destination []byte) (_ []byte, err error) {
 	v.visit(ctx.Filepath, string(destination), ctx.LineNumbers)
-	return destination, nil
+	if !v.fixRedirects {
+		return destination, nil
+	}
+
+	target, ok := v.redirectTarget(string(destination))
+	if !ok {
+		return destination, nil
+	}
+	level.Info(v.logger).Log("msg", "fixing permanently redirected link", "file", ctx.Filepath, "url", string(destination), "target", target)
+	return []byte(target), nil
+}
+
+// redirectTarget returns final URL of permanently redirected link, if any. Fragment of link is preserved.
+func (v *validator) redirectTarget(dest string) (string, bool) {
+	page, fragment := splitFragment(dest)
+	v.rMu.RLock()
+	defer v.rMu.RUnlock()
+	target, ok := v.remoteRedirects[page]
+	if !ok {
+		return "", false
+	}
+	if fragment != "" && !strings.Contains(target, "#") {
+		target += "#" + fragment
+	}
+	return target, true
 }
 
 func (v *validator) Close(ctx mdformatter.SourceContext) error {
 	v.c.Wait()
+	for _, val := range v.validateConfig.Validators {
+		if g := val.ghValidator._graphql; g != nil {
+			g.flush(v)
+		}
+	}
 
+	v.futureMu.Lock()
 	var keys []futureKey
-	for k := range v.destFutures {
+	futures := map[futureKey]*futureResult{}
+	for k, f := range v.destFutures {
 		if k.filepath != ctx.Filepath {
 			continue
 		}
 		keys = append(keys, k)
+		futures[k] = f
+		// File is done, so the same validator can be used to process it again, e.g. to fix redirects.
+		delete(v.destFutures, k)
 	}
+	v.futureMu.Unlock()
 	sort.Slice(keys, func(i, j int) bool {
 		return keys[i].filepath+keys[i].dest > keys[j].filepath+keys[j].dest
 	})
 
-	merr := merrors.New()
+	merr, warnings := merrors.New(), merrors.New()
 	base, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("resolve working dir: %w", err)
@@ -386,23 +732,235 @@ func (v *validator) Close(ctx mdformatter.SourceContext) error {
 	}
 
 	for _, k := range keys {
-		f := v.destFutures[k]
+		f := futures[k]
+		if f.unverified {
+			level.Warn(v.logger).Log("msg", "link unverified; no cached result in cache-only mode", "file", fmt.Sprintf("%v:%v", path, k.lineNumbers), "url", k.dest)
+			v.result(k, LinkStatusUnverified, nil)
+			continue
+		}
+		if !v.fixRedirects {
+			if target, ok := v.redirectTarget(k.dest); ok {
+				level.Warn(v.logger).Log("msg", "link permanently redirected; use --links.fix-redirects to update it", "file", fmt.Sprintf("%v:%v", path, k.lineNumbers), "url", k.dest, "target", target)
+			}
+		}
 		if err := f.resultFn(); err != nil {
+			errs, status := merr, LinkStatusInvalid
+			if f.severity == SeverityWarn && v.failOn == SeverityError {
+				errs, status = warnings, LinkStatusWarning
+				v.l.warnedLinks.Inc()
+			}
+			v.result(k, status, err)
 			if f.cases == 1 {
-				merr.Add(fmt.Errorf("%v:%v: %w", path, k.lineNumbers, err))
+				errs.Add(fmt.Errorf("%v:%v: %w", path, k.lineNumbers, err))
 				continue
 			}
-			merr.Add(fmt.Errorf("%v:%v (%v occurrences): %w", path, k.lineNumbers, f.cases, err))
+			errs.Add(fmt.Errorf("%v:%v (%v occurrences): %w", path, k.lineNumbers, f.cases, err))
+			continue
+		}
+		if f.skipped {
+			v.result(k, LinkStatusSkipped, nil)
+			continue
+		}
+		v.result(k, LinkStatusValid, nil)
+	}
+	if errs := warnings.Err(); errs != nil {
+		if v.warnings == nil {
+			level.Warn(v.logger).Log("msg", "links with warn severity failed", "file", path, "err", errs)
+		} else {
+			for _, err := range errs.Errors() {
+				v.warnings.add(v.redactor.redactErr(err))
+			}
 		}
 	}
-	return merr.Err()
+	return v.redactor.redactErr(merr.Err())
+}
+
+// result passes result of link to result function, if any.
+func (v *validator) result(k futureKey, status LinkStatus, err error) {
+	if v.resultFn == nil {
+		return
+	}
+	v.resultFn(LinkResult{Filepath: k.filepath, Destination: k.dest, LineNumbers: k.lineNumbers, Status: status, Err: v.redactor.redactErr(err)})
+}
+
+// markValid marks given page as valid and caches it, if cache is configured.
+func (v *validator) markValid(u string, statusCode int, headers *http.Header) {
+	v.remoteLinks[u] = nil
+	r := cache.Result{StatusCode: statusCode}
+	if headers != nil {
+		r.ETag = headers.Get("ETag")
+		r.LastModified = headers.Get("Last-Modified")
+	}
+	v.cacheResult(u, r)
+}
+
+// markInvalid marks given page as invalid with given error and caches the failure, if cache is configured.
+func (v *validator) markInvalid(u string, statusCode int, cause error, err error) {
+	v.remoteLinks[u] = err
+	v.cacheResult(u, cache.Result{StatusCode: statusCode, ErrorClass: errorClass(statusCode, cause)})
+}
+
+func (v *validator) cacheResult(u string, r cache.Result) {
+	if v.storage == nil {
+		return
+	}
+	if err := v.storage.CacheResult(u, r); err != nil {
+		level.Warn(v.logger).Log("msg", "remote link not saved to cache", "url", u, "err", err)
+	}
+}
+
+func errorClass(statusCode int, err error) cache.ErrorClass {
+	if statusCode != 0 {
+		return cache.ErrorClassStatus
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return cache.ErrorClassTimeout
+	}
+	return cache.ErrorClassNetwork
+}
+
+// checkOffline checks remote link matching given validator (if any) without network access. In cache-only mode, result
+// is taken from cache, with expired successes still considered valid. Links without cached result are unverified.
+// NOTE: futureMu has to be held by the caller.
+func (v *validator) checkOffline(k futureKey, validator Validator) {
+	if v.mode == ModeLocalOnly {
+		v.l.offlineSkippedLinks.Inc()
+		v.destFutures[k].skipped = true
+		return
+	}
+
+	page, fragment := splitFragment(k.dest)
+	needFragment := fragment != "" && !unverifiableFragmentRe.MatchString(fragment)
+	if gh, ok := validator.(GitHubPullsIssuesValidator); ok && gh._graphql != nil {
+		// Cached by referenced issue, pull request or comment, as in checkPrecise.
+		ref, err := gh.parseRef(k.dest)
+		if err != nil {
+			v.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, parsing number: %w", k.dest, err) }
+			return
+		}
+		page, needFragment = ref.cacheKey(), false
+	}
+	e, fresh, err := v.storage.Lookup(page)
+	if err == nil && e != nil && e.ErrorClass == cache.ErrorClassNone && needFragment {
+		// Page exists, but only link with fragment cached after successful check proves that fragment exists.
+		e, fresh, err = v.storage.Lookup(k.dest)
+	}
+	switch {
+	case err != nil:
+		v.destFutures[k].resultFn = func() error { return fmt.Errorf("cache lookup %v: %w", k.dest, err) }
+	case e == nil:
+		v.l.offlineSkippedLinks.Inc()
+		v.destFutures[k].unverified = true
+	case e.ErrorClass == cache.ErrorClassNone:
+		v.l.roundTripCachedLinks.Inc()
+	case fresh:
+		v.l.roundTripCachedLinks.Inc()
+		v.destFutures[k].resultFn = func() error { return cachedFailureErr(page, e) }
+	default:
+		v.l.offlineSkippedLinks.Inc()
+		v.destFutures[k].unverified = true
+	}
+}
+
+// cachedFailureErr returns error for cached failure of a page.
+func cachedFailureErr(page string, e *cache.Entry) error {
+	return fmt.Errorf("%q not accessible (cached failure from %v); status code %v, error class %v", page, e.Timestamp.UTC().Format(time.RFC3339), e.StatusCode, e.ErrorClass)
+}
+
+// visitRemote requests given page. If body is not needed, only headers are fetched. If expired cache entry is given,
+// conditional request is made, so 304 Not Modified response means page is still valid.
+// NOTE: rMu has to be held by the caller.
+func (v *validator) visitRemote(page string, needBody bool, acceptStatusCodes []int, expired *cache.Entry) error {
+	v.remoteVisits[page] = v.remoteVisits[page] || needBody
+	// Mark page as visited, so other links to it (e.g. with different fragment) wait for the same result.
+	if _, ok := v.remoteLinks[page]; !ok {
+		v.remoteLinks[page] = nil
+	}
+
+	ctx := colly.NewContext()
+	if len(acceptStatusCodes) > 0 {
+		ctx.Put(acceptStatusCodesKey, acceptStatusCodes)
+	}
+	method := http.MethodGet
+	switch {
+	case needBody:
+		ctx.Put(needBodyKey, true)
+	default:
+		if u, err := url.Parse(page); err == nil && v.hosts.forHost(u.Host).headFirst {
+			method = http.MethodHead
+		}
+	}
+	var hdr http.Header
+	if expired != nil && !needBody && (expired.ETag != "" || expired.LastModified != "") {
+		hdr = http.Header{}
+		if expired.ETag != "" {
+			hdr.Set("If-None-Match", expired.ETag)
+		}
+		if expired.LastModified != "" {
+			hdr.Set("If-Modified-Since", expired.LastModified)
+		}
+		ctx.Put(revalidateKey, expired)
+	}
+	return v.c.Request(method, page, nil, ctx, hdr)
+}
+
+func containsInt(s []int, i int) bool {
+	for _, e := range s {
+		if e == i {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteResult returns result of remote link check. If link has fragment and page was fetched and is HTML, it also
+// checks if page has element with such id or name. Once fragment is found, link is cached, similar to pages.
+func (v *validator) remoteResult(dest string) error {
+	v.rMu.Lock()
+	defer v.rMu.Unlock()
+
+	page, fragment := splitFragment(dest)
+	if err := v.remoteLinks[page]; err != nil {
+		return err
+	}
+	ids, ok := v.remoteIDs[page]
+	if !ok || fragment == "" || unverifiableFragmentRe.MatchString(fragment) {
+		return nil
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	_, found := ids[fragment]
+	if !found {
+		_, found = ids[gitHubUserContentPrefix+fragment]
+	}
+	if !found {
+		return fmt.Errorf("%q: %w", dest, RemoteIDNotFoundErr)
+	}
+	if v.storage != nil {
+		if err := v.storage.CacheResult(dest, cache.Result{StatusCode: http.StatusOK}); err != nil {
+			return fmt.Errorf("remote link not saved to cache %v: %w", dest, err)
+		}
+	}
+	return nil
+}
+
+// splitFragment splits URL into page URL and fragment (without #).
+func splitFragment(dest string) (page string, fragment string) {
+	i := strings.Index(dest, "#")
+	if i < 0 {
+		return dest, ""
+	}
+	return dest[:i], dest[i+1:]
 }
 
 func (v *validator) checkLocal(k futureKey) bool {
 	v.l.localLinksChecked.Inc()
 	// Check if link is email address.
 	if email := strings.TrimPrefix(k.dest, "mailto:"); email != k.dest {
-		if isValidEmail(email) {
+		// Domain is checked with DNS lookup, so only with network access.
+		if isValidEmail(email, v.mode == ModeFull) {
 			return true
 		}
 		v.destFutures[k].resultFn = func() error { return fmt.Errorf("provided mailto link is not a valid email, got %v", k.dest) }
@@ -413,7 +971,7 @@ func (v *validator) checkLocal(k futureKey) bool {
 	newDest := absLocalLink(v.anchorDir, k.filepath, k.dest)
 
 	// Local link. Check if exists.
-	if err := v.localLinks.Lookup(newDest); err != nil {
+	if err := v.localLinks.Lookup(v.anchorDir, newDest); err != nil {
 		v.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, normalized to: %w", k.dest, err) }
 		return false
 	}
@@ -428,10 +986,10 @@ func (v *validator) visit(filepath string, dest string, lineNumbers string) {
 		v.destFutures[k].cases++
 		return
 	}
-	v.destFutures[k] = &futureResult{cases: 1, resultFn: func() error { return nil }}
+	v.destFutures[k] = &futureResult{cases: 1, resultFn: func() error { return nil }, severity: v.validateConfig.severityForURL(dest)}
+	remote := remoteLinkPrefixRe.MatchString(dest)
 	if !v.validateConfig.ExplicitLocalValidators {
-		matches := remoteLinkPrefixRe.FindAllStringIndex(dest, 1)
-		if matches == nil {
+		if !remote {
 			v.checkLocal(k)
 			return
 		}
@@ -439,6 +997,15 @@ func (v *validator) visit(filepath string, dest string, lineNumbers string) {
 	}
 
 	validator := v.validateConfig.GetValidatorForURL(dest)
+	switch validator.(type) {
+	case IgnoreValidator, LocalRepoValidator:
+		// No network access needed.
+	default:
+		if remote && v.mode != ModeFull {
+			v.checkOffline(k, validator)
+			return
+		}
+	}
 	if validator != nil {
 		matched, err := validator.IsValid(k, v)
 		if matched && err == nil {
@@ -447,8 +1014,8 @@ func (v *validator) visit(filepath string, dest string, lineNumbers string) {
 	}
 }
 
-// isValidEmail checks email structure and domain.
-func isValidEmail(email string) bool {
+// isValidEmail checks email structure and, if checkDomain is true, whether its domain accepts emails.
+func isValidEmail(email string, checkDomain bool) bool {
 	// Check length.
 	if len(email) < 3 && len(email) > 254 {
 		return false
@@ -458,6 +1025,9 @@ func isValidEmail(email string) bool {
 	if !emailRe.MatchString(email) {
 		return false
 	}
+	if !checkDomain {
+		return true
+	}
 	// Check email domain.
 	domain := strings.Split(email, "@")
 	mx, err := net.LookupMX(domain[1])
@@ -470,7 +1040,7 @@ func isValidEmail(email string) bool {
 type localLinksCache map[string]*[]string
 
 // Lookup looks for given link in local anchorDir. It returns error if link can't be found.
-func (l localLinksCache) Lookup(absLink string) error {
+func (l localLinksCache) Lookup(anchorDir string, absLink string) error {
 	splitWith := "#"
 	if strings.Contains(absLink, "/#") {
 		splitWith = "/#"
@@ -479,13 +1049,13 @@ func (l localLinksCache) Lookup(absLink string) error {
 	absLinkSplit := strings.Split(absLink, splitWith)
 	ids, ok := l[absLinkSplit[0]]
 	if !ok {
-		if err := l.addRelLinks(absLinkSplit[0]); err != nil {
+		if err := l.addRelLinks(anchorDir, absLinkSplit[0]); err != nil {
 			return err
 		}
 		ids = l[absLinkSplit[0]]
 	}
 	if ids == nil {
-		return fmt.Errorf("%v: %w", absLinkSplit[0], FileNotFoundErr)
+		return fileNotFoundErr(anchorDir, absLinkSplit[0])
 	}
 
 	if len(absLinkSplit) == 1 {
@@ -497,10 +1067,10 @@ func (l localLinksCache) Lookup(absLink string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("link %v, existing ids: %v: %w", absLink, *ids, IDNotFoundErr)
+	return idNotFoundErr(absLink, absLinkSplit[1], *ids)
 }
 
-func (l localLinksCache) addRelLinks(localLink string) error {
+func (l localLinksCache) addRelLinks(anchorDir string, localLink string) error {
 	// Add item for negative caching.
 	l[localLink] = nil
 
@@ -511,6 +1081,10 @@ func (l localLinksCache) addRelLinks(localLink string) error {
 		}
 		return fmt.Errorf("failed to stat %v: %w", localLink, err)
 	}
+	if !hasExactCase(anchorDir, localLink) {
+		// Found only thanks to case-insensitive file system, so treat it as missing.
+		return nil
+	}
 
 	if st.IsDir() {
 		// Dir present, cache presence.
@@ -4,16 +4,24 @@
 package linktransformer
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bwplotka/mdox/pkg/cache"
+	"github.com/bwplotka/mdox/pkg/clilog"
 	"github.com/bwplotka/mdox/pkg/mdformatter"
 	"github.com/efficientgo/core/testutil"
 	"github.com/go-kit/log"
@@ -120,6 +128,294 @@ func TestLocalizer_TransformDestination(t *testing.T) {
 
 `, tmpDir, tmpDir), diff.String())
 	})
+
+	t.Run("multiple targets from config", func(t *testing.T) {
+		repoDir := filepath.Join(tmpDir, "repo2")
+		testutil.Ok(t, os.MkdirAll(filepath.Join(repoDir, "docs"), os.ModePerm))
+		testutil.Ok(t, os.MkdirAll(filepath.Join(repoDir, "Documentation"), os.ModePerm))
+		testutil.Ok(t, os.WriteFile(filepath.Join(repoDir, "docs", "guide.md"), []byte("# Guide\n"), os.ModePerm))
+		testutil.Ok(t, os.WriteFile(filepath.Join(repoDir, "Documentation", "api.md"), []byte("# API\n"), os.ModePerm))
+		testFile := filepath.Join(repoDir, "README.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](https://thanos.io/tip/guide.md) [2](https://thanos.io/v0.30/guide.md#guide) [3](https://thanos.io/tip/missing.md)
+
+[4](https://prometheus-operator.dev/docs/api.md#api) [5](https://prometheus-operator.dev/docs/guide.md) [6](https://example.com/docs/api.md)
+`), os.ModePerm))
+
+		_, err := NewLocalizerFromConfig(logger, []byte("targets:\n  - dir: docs\n"), repoDir)
+		testutil.NotOk(t, err)
+
+		l, err := NewLocalizerFromConfig(logger, []byte(`targets:
+  - addressRegex: thanos\.io/(tip|v[0-9.]+)/
+    dir: docs/
+  - addressRegex: prometheus-operator\.dev/docs/
+    dir: Documentation
+  - addressRegex: prometheus-operator\.dev/docs/
+    dir: docs
+`), repoDir)
+		testutil.Ok(t, err)
+		testutil.Ok(t, mdformatter.Format(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(l)))
+
+		b, err := os.ReadFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, `[1](docs/guide.md) [2](docs/guide.md#guide) [3](https://thanos.io/tip/missing.md)
+
+[4](Documentation/api.md#api) [5](docs/guide.md) [6](https://example.com/docs/api.md)
+`, string(b))
+	})
+}
+
+func TestMovedFixer_TransformDestination(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-moved-fixer")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, os.RemoveAll(tmpDir)) })
+
+	repoDir := filepath.Join(tmpDir, "repo")
+	testutil.Ok(t, os.MkdirAll(filepath.Join(repoDir, "docs", "olddir"), os.ModePerm))
+	git := func(args ...string) {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...).CombinedOutput()
+		testutil.Ok(t, err, string(out))
+	}
+	write := func(file, content string) {
+		t.Helper()
+		testutil.Ok(t, os.WriteFile(filepath.Join(repoDir, "docs", file), []byte(content), os.ModePerm))
+	}
+
+	git("init", "-q")
+	write("old.md", "# Old\n\nContent of old file.\n")
+	write(filepath.Join("olddir", "x.md"), "# X\n\nContent of file in old directory.\n")
+	write("dup.md", "# Dup\n\nContent of duplicated file.\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "add")
+	git("mv", "docs/old.md", "docs/old2.md")
+	git("commit", "-q", "-m", "rename")
+	testutil.Ok(t, os.MkdirAll(filepath.Join(repoDir, "docs", "new"), os.ModePerm))
+	git("mv", "docs/old2.md", "docs/new/old.md")
+	git("mv", "docs/olddir", "docs/newdir")
+	git("mv", "docs/dup.md", "docs/dup1.md")
+	git("commit", "-q", "-m", "move")
+	write("dup.md", "# Dup\n\nContent of duplicated file, again.\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "add again")
+	git("mv", "docs/dup.md", "docs/dup2.md")
+	git("commit", "-q", "-m", "move again")
+
+	write("doc.md", `[1](old.md#old) [2](olddir/x.md) [3](olddir) [4](/old.md) [5](does_not_exists.md) [6](new/old.md)
+`)
+	write("ambiguous.md", `[1](dup.md) [2](old.md)
+`)
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	anchorDir := filepath.Join(repoDir, "docs")
+	testFile := filepath.Join(anchorDir, "doc.md")
+	wdir, err := os.Getwd()
+	testutil.Ok(t, err)
+	relDirPath, err := filepath.Rel(wdir, anchorDir)
+	testutil.Ok(t, err)
+
+	f, err := NewMovedFixer(context.TODO(), logger, anchorDir)
+	testutil.Ok(t, err)
+	ambiguousFile := filepath.Join(anchorDir, "ambiguous.md")
+	err = mdformatter.Format(context.TODO(), logger, []string{testFile, ambiguousFile}, mdformatter.WithLinkTransformer(f))
+	testutil.NotOk(t, err)
+	testutil.Equals(t, fmt.Sprintf("%v: %v:1: link dup.md, target moved to multiple locations: docs/dup1.md, docs/dup2.md", ambiguousFile, filepath.Join(relDirPath, "ambiguous.md")), err.Error())
+
+	b, err := os.ReadFile(testFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, `[1](new/old.md#old) [2](newdir/x.md) [3](newdir) [4](/new/old.md) [5](does_not_exists.md) [6](new/old.md)
+`, string(b))
+}
+
+func TestLinkGraph_Orphans(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-link-graph")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, os.RemoveAll(tmpDir)) })
+
+	testutil.Ok(t, os.MkdirAll(filepath.Join(tmpDir, "docs", "sub"), os.ModePerm))
+	files := map[string]string{
+		"README.md":            "[1](docs/a.md) [2](docs/sub) [3](https://example.com/docs/c.md)\n",
+		"docs/a.md":            "# A\n\n![1](img.png) [2](#a) [3](/docs/b.md?plain=1#b)\n",
+		"docs/b.md":            "# B\n",
+		"docs/c.md":            "[1](a.md) [2](orphan.png)\n",
+		"docs/sub/README.md":   "[1](../d.md)\n",
+		"docs/d.md":            "# D\n",
+		"docs/img.png":         "",
+		"docs/orphan.png":      "",
+		"docs/sub/unlinked.md": "# Unlinked\n",
+	}
+	var all, mdFiles []string
+	for f, content := range files {
+		p := filepath.Join(tmpDir, filepath.FromSlash(f))
+		testutil.Ok(t, os.WriteFile(p, []byte(content), os.ModePerm))
+		all = append(all, p)
+		if filepath.Ext(p) == ".md" {
+			mdFiles = append(mdFiles, p)
+		}
+	}
+
+	g := NewLinkGraph(tmpDir)
+	_, err = mdformatter.IsFormatted(context.TODO(), log.NewNopLogger(), mdFiles, mdformatter.WithLinkTransformer(g))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		filepath.Join(tmpDir, "docs", "c.md"),
+		filepath.Join(tmpDir, "docs", "orphan.png"),
+		filepath.Join(tmpDir, "docs", "sub", "unlinked.md"),
+	}, g.Orphans([]string{filepath.Join(tmpDir, "README.md")}, all))
+}
+
+func TestLinkInventory(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-link-inventory")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, os.RemoveAll(tmpDir)) })
+
+	testutil.Ok(t, os.MkdirAll(filepath.Join(tmpDir, "docs"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("# Root\n\n[Docs *A*](docs/a.md) [Missing](docs/missing.md)\n"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "docs", "a.md"), []byte("# A\n\n![Logo](logo.png) [Root](/README.md#root) [1](https://example.com/a) [2](https://example.com/b)\n\n<https://example.org>\n"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "docs", "logo.png"), nil, os.ModePerm))
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	inv := NewLinkInventory(tmpDir)
+	v, err := NewValidator(context.TODO(), logger, nil, tmpDir, nil, nil, WithMode(ModeLocalOnly), WithResultFn(inv.SetResult))
+	testutil.Ok(t, err)
+	_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{filepath.Join(tmpDir, "README.md"), filepath.Join(tmpDir, "docs", "a.md")}, mdformatter.WithLinkTransformer(NewChain(inv, v)))
+	testutil.NotOk(t, err)
+
+	testutil.Equals(t, []Link{
+		{Filepath: "README.md", LineNumbers: "3", Text: "Docs A", Destination: "docs/a.md", Type: LinkTypeInternal, Status: LinkStatusValid},
+		{Filepath: "README.md", LineNumbers: "3", Text: "Missing", Destination: "docs/missing.md", Type: LinkTypeInternal, Status: LinkStatusInvalid,
+			Error: fmt.Sprintf("link docs/missing.md, normalized to: %v: file not found", filepath.Join(tmpDir, "docs", "missing.md"))},
+		{Filepath: "docs/a.md", LineNumbers: "3", Text: "Logo", Destination: "logo.png", Type: LinkTypeInternal, Status: LinkStatusValid},
+		{Filepath: "docs/a.md", LineNumbers: "3", Text: "Root", Destination: "/README.md#root", Type: LinkTypeInternal, Status: LinkStatusValid},
+		{Filepath: "docs/a.md", LineNumbers: "3", Text: "1", Destination: "https://example.com/a", Type: LinkTypeExternal, Status: LinkStatusSkipped},
+		{Filepath: "docs/a.md", LineNumbers: "3", Text: "2", Destination: "https://example.com/b", Type: LinkTypeExternal, Status: LinkStatusSkipped},
+		{Filepath: "docs/a.md", LineNumbers: "5", Text: "https://example.org", Destination: "https://example.org", Type: LinkTypeExternal, Status: LinkStatusSkipped},
+	}, inv.Links())
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, inv.WriteCSV(&b))
+	testutil.Equals(t, fmt.Sprintf(`file,line,text,destination,type,status,error
+README.md,3,Docs A,docs/a.md,internal,valid,
+README.md,3,Missing,docs/missing.md,internal,invalid,"link docs/missing.md, normalized to: %v: file not found"
+docs/a.md,3,Logo,logo.png,internal,valid,
+docs/a.md,3,Root,/README.md#root,internal,valid,
+docs/a.md,3,1,https://example.com/a,external,skipped,
+docs/a.md,3,2,https://example.com/b,external,skipped,
+docs/a.md,5,https://example.org,https://example.org,external,skipped,
+`, filepath.Join(tmpDir, "docs", "missing.md")), b.String())
+
+	b.Reset()
+	testutil.Ok(t, inv.WriteDOT(&b))
+	testutil.Equals(t, `digraph links {
+  "README.md" -> "docs/a.md";
+  "README.md" -> "docs/missing.md" [color=red];
+  "docs/a.md" -> "README.md";
+  "docs/a.md" -> "docs/logo.png";
+  "docs/a.md" -> "https://example.com" [style=dashed, label=2];
+  "docs/a.md" -> "https://example.org" [style=dashed];
+}
+`, b.String())
+
+	b.Reset()
+	testutil.Ok(t, inv.WriteJSON(&b))
+	var links []Link
+	testutil.Ok(t, json.Unmarshal(b.Bytes(), &links))
+	testutil.Equals(t, inv.Links(), links)
+}
+
+func TestRewriter_TransformDestination(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-rewriter")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, os.RemoveAll(tmpDir)) })
+
+	testFile := filepath.Join(tmpDir, "doc.md")
+	testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](https://old.example.io/guide.md#setup) [2](https://github.com/old-org/repo/issues/1) [3](https://example.com/old.example.io)
+
+![4](https://old.example.io/img/logo.png)
+`), os.ModePerm))
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	t.Run("invalid regex", func(t *testing.T) {
+		_, err := NewRewriter(logger, []byte("rewrites:\n  - regex: \"(\"\n    replacement: x\n"))
+		testutil.NotOk(t, err)
+	})
+
+	t.Run("rewrites with capture groups", func(t *testing.T) {
+		r, err := NewRewriter(logger, []byte(`rewrites:
+  - regex: ^https://old\.example\.io/(?P<path>.*)$
+    replacement: https://docs.example.com/${path}
+  - regex: ^https://github\.com/old-org/
+    replacement: https://github.com/new-org/
+  - regex: ^https://docs\.example\.com/img/
+    replacement: /static/
+`))
+		testutil.Ok(t, err)
+		testutil.Ok(t, mdformatter.Format(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(r)))
+		b, err := os.ReadFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, `[1](https://docs.example.com/guide.md#setup) [2](https://github.com/new-org/repo/issues/1) [3](https://example.com/old.example.io)
+
+![4](/static/logo.png)
+`, string(b))
+	})
+}
+
+func TestGlobalizer_TransformDestination(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-globalizer")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, os.RemoveAll(tmpDir)) })
+
+	testutil.Ok(t, os.MkdirAll(filepath.Join(tmpDir, "repo", "docs"), os.ModePerm))
+	testFile := filepath.Join(tmpDir, "repo", "docs", "README.md")
+	content := `# Docs
+
+[1](install.md#setup) [2](/README.md) [3](.) [4](#docs) [5](../CONTRIBUTING.md?plain=1) [6](../../outside.md) [7](https://example.com/a.md)
+
+![8](img/logo.png)
+`
+	testutil.Ok(t, os.WriteFile(testFile, []byte(content), os.ModePerm))
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	anchorDir := filepath.Join(tmpDir, "repo")
+	output := filepath.Join(tmpDir, "out.md")
+	t.Run("relative base", func(t *testing.T) {
+		_, err := NewGlobalizer(logger, "docs/", anchorDir, nil)
+		testutil.NotOk(t, err)
+	})
+
+	t.Run("repository base", func(t *testing.T) {
+		g, err := NewGlobalizer(logger, "https://github.com/bwplotka/mdox/blob/main", anchorDir, nil)
+		testutil.Ok(t, err)
+		testutil.Ok(t, mdformatter.FormatInto(context.TODO(), logger, testFile, output, mdformatter.WithLinkTransformer(g)))
+
+		b, err := os.ReadFile(output)
+		testutil.Ok(t, err)
+		testutil.Equals(t, `# Docs
+
+[1](https://github.com/bwplotka/mdox/blob/main/docs/install.md#setup) [2](https://github.com/bwplotka/mdox/blob/main/README.md) [3](https://github.com/bwplotka/mdox/blob/main/docs/README.md) [4](#docs) [5](https://github.com/bwplotka/mdox/blob/main/CONTRIBUTING.md?plain=1) [6](../../outside.md) [7](https://example.com/a.md)
+
+![8](https://github.com/bwplotka/mdox/blob/main/docs/img/logo.png)
+`, string(b))
+
+		// Source file is not modified.
+		b, err = os.ReadFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, content, string(b))
+	})
+
+	t.Run("website base with extension mapping", func(t *testing.T) {
+		g, err := NewGlobalizer(logger, "https://example.com/v1/", anchorDir, map[string]string{".md": "/"})
+		testutil.Ok(t, err)
+		testutil.Ok(t, mdformatter.FormatInto(context.TODO(), logger, testFile, output, mdformatter.WithLinkTransformer(g)))
+
+		b, err := os.ReadFile(output)
+		testutil.Ok(t, err)
+		testutil.Equals(t, `# Docs
+
+[1](https://example.com/v1/docs/install/#setup) [2](https://example.com/v1/README/) [3](https://example.com/v1/docs/README/) [4](#docs) [5](https://example.com/v1/CONTRIBUTING/?plain=1) [6](../../outside.md) [7](https://example.com/a.md)
+
+![8](https://example.com/v1/docs/img/logo.png)
+`, string(b))
+	})
 }
 
 func TestValidator_TransformDestination(t *testing.T) {
@@ -250,11 +546,37 @@ func TestValidator_TransformDestination(t *testing.T) {
 		testutil.NotOk(t, err)
 
 		testutil.Equals(t, fmt.Sprintf("%v: 4 errors: "+
-			"%v:3: link ../test2/invalid-local-links.md, normalized to: %v/repo/docs/test2/invalid-local-links.md: file not found; "+
+			"%v:3: link ../test2/invalid-local-links.md, normalized to: %v/repo/docs/test2/invalid-local-links.md: file not found (did you mean %v/repo/docs/test/invalid-local-links.md?); "+
 			"%v:3: link ../test/invalid-local-links.md#not-yolo, normalized to: link %v/repo/docs/test/invalid-local-links.md#not-yolo, existing ids: [yolo]: file exists, but does not have such id; "+
 			"%v:3: link ../test/doc.md, normalized to: %v/repo/docs/test/doc.md: file not found; "+
 			"%v:3: link #not-yolo, normalized to: link %v/repo/docs/test/invalid-local-links.md#not-yolo, existing ids: [yolo]: file exists, but does not have such id",
-			tmpDir+filePath, relDirPath+filePath, tmpDir, relDirPath+filePath, tmpDir, relDirPath+filePath, tmpDir, relDirPath+filePath, tmpDir), err.Error())
+			tmpDir+filePath, relDirPath+filePath, tmpDir, tmpDir, relDirPath+filePath, tmpDir, relDirPath+filePath, tmpDir, relDirPath+filePath, tmpDir), err.Error())
+	})
+
+	t.Run("check suggestions for invalid local links", func(t *testing.T) {
+		testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "repo", "docs", "test", "suggest-target.md"), []byte("# Getting Started\n\n# Install\n\n# Configure\n\n# Run\n\n# Debug\n\n# Contribute\n"), os.ModePerm))
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "suggest.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](sugest-target.md) [2](Suggest-Target.md) [3](suggest-target.md#getting-startd) [4](suggest-target.md#Install) [5](suggest-target.md#license) [6](../Test/suggest-target.md) [7](../../Docs/tset/suggest-target.md)
+`), os.ModePerm))
+		filePath := "/repo/docs/test/suggest.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte(""), anchorDir, nil),
+		))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%[1]v: 7 errors: "+
+			"%[2]v:1: link suggest-target.md#license, normalized to: link %[3]v/suggest-target.md#license, 6 existing ids: file exists, but does not have such id; "+
+			"%[2]v:1: link suggest-target.md#getting-startd, normalized to: link %[3]v/suggest-target.md#getting-startd, did you mean #getting-started?: file exists, but does not have such id; "+
+			"%[2]v:1: link suggest-target.md#Install, normalized to: link %[3]v/suggest-target.md#Install, id differs only in case from #install: file exists, but does not have such id; "+
+			"%[2]v:1: link sugest-target.md, normalized to: %[3]v/sugest-target.md: file not found (did you mean %[3]v/suggest-target.md?); "+
+			"%[2]v:1: link Suggest-Target.md, normalized to: %[3]v/Suggest-Target.md: file not found (path element Suggest-Target.md differs only in case from suggest-target.md of %[3]v/suggest-target.md, which works on case-insensitive file systems only); "+
+			"%[2]v:1: link ../Test/suggest-target.md, normalized to: %[4]v/docs/Test/suggest-target.md: file not found (path element Test differs only in case from test of %[3]v/suggest-target.md, which works on case-insensitive file systems only); "+
+			"%[2]v:1: link ../../Docs/tset/suggest-target.md, normalized to: %[4]v/Docs/tset/suggest-target.md: file not found (did you mean %[3]v/suggest-target.md?)",
+			tmpDir+filePath, relDirPath+filePath, tmpDir+"/repo/docs/test", tmpDir+"/repo"), err.Error())
 	})
 
 	t.Run("check valid email link", func(t *testing.T) {
@@ -327,6 +649,687 @@ func TestValidator_TransformDestination(t *testing.T) {
 		testutil.Ok(t, err)
 	})
 
+	t.Run("check remote link fragments", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch r.URL.Path {
+			case "/page":
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(`<html><body><h2 id="relabel_config">Relabel</h2><a name="legacy"></a><h2 id="user-content-yolo-2">Yolo 2</h2></body></html>`))
+			case "/raw":
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("# Not parsed"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "remote-fragments.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/page#relabel_config) [2](%[1]s/page#legacy)\n\n[3](%[1]s/page#yolo-2) [4](%[1]s/page#L10-L20)\n\n[5](%[1]s/raw#anything) [6](%[1]s/page#does-not-exists)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/remote-fragments.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte(""), anchorDir, nil),
+		))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%v%v: %v%v:5: %q: page exists, but does not have such id", tmpDir, filePath, relDirPath, filePath, srv.URL+"/page#does-not-exists"), err.Error())
+		// Each page is fetched only once.
+		testutil.Equals(t, 2, requests)
+	})
+	t.Run("check remote links with HEAD first by default and accepted status codes", func(t *testing.T) {
+		var mu sync.Mutex
+		requests := map[string]int{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[r.Method+" "+r.URL.Path]++
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/file.pdf":
+			case "/no-head":
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				}
+			case "/protected":
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "remote-head.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/file.pdf) [2](%[1]s/no-head) [3](%[1]s/protected)\n", srv.URL)), os.ModePerm))
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte(fmt.Sprintf("version: 1\n\nvalidators:\n  - regex: '%s/protected'\n    type: 'roundtrip'\n    acceptStatusCodes: [403]\n  - regex: '.*'\n    type: 'roundtrip'\n", regexp.QuoteMeta(srv.URL))), anchorDir, nil),
+		))
+		testutil.Ok(t, err)
+		testutil.Equals(t, map[string]int{
+			"HEAD /file.pdf": 1,
+			"HEAD /no-head":  1,
+			"GET /no-head":   1,
+			// Accepted status code, no need to fallback.
+			"HEAD /protected": 1,
+		}, requests)
+
+		// Hosts mishandling HEAD requests can opt out.
+		mu.Lock()
+		requests = map[string]int{}
+		mu.Unlock()
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte(fmt.Sprintf("version: 1\nhosts:\n  '127.0.0.1:*':\n    disableHeadFirst: true\n\nvalidators:\n  - regex: '%s/protected'\n    type: 'roundtrip'\n    acceptStatusCodes: [403]\n  - regex: '.*'\n    type: 'roundtrip'\n", regexp.QuoteMeta(srv.URL))), anchorDir, nil),
+		))
+		testutil.Ok(t, err)
+		testutil.Equals(t, map[string]int{
+			"GET /file.pdf":  1,
+			"GET /no-head":   1,
+			"GET /protected": 1,
+		}, requests)
+	})
+	t.Run("check remote links with host policies", func(t *testing.T) {
+		var mu sync.Mutex
+		requests := map[string]int{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[r.URL.Path]++
+			n := requests[r.URL.Path]
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/flaky":
+				if n <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			case "/limited":
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "remote-hosts.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/flaky) [2](%[1]s/limited)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/remote-hosts.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte("version: 1\nhosts:\n  '127.0.0.1:*':\n    parallelism: 1\n    timeout: 5s\n    maxRetries: 3\n    backoff:\n      initial: 1ms\n      max: 10ms\n"), anchorDir, nil),
+		))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%v: %v:1: \"%v/limited\" rate limited even after 3 retries; status code 429: Too Many Requests", tmpDir+filePath, relDirPath+filePath, srv.URL), err.Error())
+		testutil.Equals(t, map[string]int{"/flaky": 3, "/limited": 4}, requests)
+	})
+	t.Run("check remote links with too long retry-after", func(t *testing.T) {
+		var mu sync.Mutex
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "remote-retry-after.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%s/later)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/remote-retry-after.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte("version: 1\nhosts:\n  '127.0.0.1:*':\n    maxRetries: 3\n    maxRetryAfter: 10m\n"), anchorDir, nil),
+		))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%v: %v:1: \"%v/later\" rate limited, not retried as retry-after 1h0m0s too long, longer than maxRetryAfter 10m0s; status code 429: Too Many Requests", tmpDir+filePath, relDirPath+filePath, srv.URL), err.Error())
+		testutil.Equals(t, 1, requests)
+	})
+	t.Run("check remote links with credentials", func(t *testing.T) {
+		t.Setenv("MDOX_TEST_API_KEY", "api-key-secret")
+		t.Setenv("MDOX_TEST_PASSWORD", "password-secret")
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/headers":
+				if r.Header.Get("X-Api-Key") != "api-key-secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			case "/basic":
+				if u, p, ok := r.BasicAuth(); !ok || u != "mdox" || p != "password-secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			case "/bearer":
+				if r.Header.Get("Authorization") != "Bearer bearer-secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			default:
+				// Credentials are not sent to links not matching validator.
+				if r.Header.Get("Authorization") != "" || r.Header.Get("X-Api-Key") != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "remote-credentials.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/headers) [2](%[1]s/basic) [3](%[1]s/bearer) [4](%[1]s/bearer-secret)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/remote-credentials.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		u := regexp.QuoteMeta(srv.URL)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, []byte(fmt.Sprintf(`version: 1
+validators:
+  - regex: '%[1]s/headers'
+    type: 'roundtrip'
+    headers:
+      X-Api-Key: '$MDOX_TEST_API_KEY'
+  - regex: '%[1]s/basic'
+    type: 'roundtrip'
+    basicAuth:
+      username: 'mdox'
+      password: '${MDOX_TEST_PASSWORD}'
+  - regex: '%[1]s/bearer$'
+    type: 'roundtrip'
+    bearerToken: 'bearer-secret'
+`, u)), anchorDir, nil),
+		))
+		testutil.NotOk(t, err)
+		// Credentials are redacted from errors.
+		testutil.Equals(t, fmt.Sprintf("%v: %v:1: \"%v/<redacted>\" not accessible; status code 404: Not Found", tmpDir+filePath, relDirPath+filePath, srv.URL), err.Error())
+
+		_, err = ParseConfig([]byte("version: 1\nvalidators:\n  - regex: 'yolo'\n    type: 'roundtrip'\n    bearerToken: '$MDOX_TEST_NOT_SET'\n"))
+		testutil.NotOk(t, err)
+	})
+	t.Run("check links with warn severity", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "severity.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/blog/post) [2](%[1]s/docs)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/severity.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		config := []byte(fmt.Sprintf("version: 1\nvalidators:\n  - regex: '^%v/blog/'\n    type: 'roundtrip'\n    severity: 'warn'\n", regexp.QuoteMeta(srv.URL)))
+		var logs bytes.Buffer
+		v, err := NewValidator(context.TODO(), log.NewLogfmtLogger(log.NewSyncWriter(&logs)), config, anchorDir, nil, nil)
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%[1]v: %[2]v:1: \"%[3]v/docs\" not accessible; status code 404: Not Found", tmpDir+filePath, relDirPath+filePath, srv.URL), err.Error())
+		testutil.Assert(t, strings.Contains(logs.String(), `msg="links with warn severity failed"`), logs.String())
+		testutil.Assert(t, strings.Contains(logs.String(), srv.URL+"/blog/post"), logs.String())
+
+		v, err = NewValidator(context.TODO(), logger, config, anchorDir, nil, nil, WithFailOn(SeverityWarn))
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.HasPrefix(err.Error(), tmpDir+filePath+": 2 errors: "), err.Error())
+
+		_, err = NewValidator(context.TODO(), logger, []byte("version: 1\nvalidators:\n  - regex: 'x'\n    type: 'ignore'\n    severity: 'info'\n"), anchorDir, nil, nil)
+		testutil.NotOk(t, err)
+	})
+	t.Run("summarize links with warn severity of all files", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile1 := filepath.Join(tmpDir, "repo", "docs", "test", "severity1.md")
+		testutil.Ok(t, os.WriteFile(testFile1, []byte(fmt.Sprintf("[1](%[1]s/blog/1) [2](%[1]s/blog/2)\n", srv.URL)), os.ModePerm))
+		testFile2 := filepath.Join(tmpDir, "repo", "docs", "test", "severity2.md")
+		testutil.Ok(t, os.WriteFile(testFile2, []byte(fmt.Sprintf("[3](%[1]s/blog/3)\n", srv.URL)), os.ModePerm))
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		config := []byte(fmt.Sprintf("version: 1\nvalidators:\n  - regex: '^%v/blog/'\n    type: 'roundtrip'\n    severity: 'warn'\n", regexp.QuoteMeta(srv.URL)))
+		var logs bytes.Buffer
+		warnings := &Warnings{}
+		v, err := NewValidator(context.TODO(), log.NewLogfmtLogger(log.NewSyncWriter(&logs)), config, anchorDir, nil, nil, WithWarnings(warnings))
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile1, testFile2}, mdformatter.WithLinkTransformer(v))
+		testutil.Ok(t, err)
+		// Warnings are not logged per file, but summarized.
+		testutil.Equals(t, "", logs.String())
+		testutil.Equals(t, 3, warnings.Len())
+
+		var summary bytes.Buffer
+		warnings.Log(clilog.New(&summary))
+		testutil.Equals(t, fmt.Sprintf("warn: 3 links with warn severity failed: 3 errors:\n"+
+			"\t%[1]v/repo/docs/test/severity1.md:1: \"%[2]v/blog/2\" not accessible; status code 404: Not Found\n"+
+			"\t%[1]v/repo/docs/test/severity1.md:1: \"%[2]v/blog/1\" not accessible; status code 404: Not Found\n"+
+			"\t%[1]v/repo/docs/test/severity2.md:1: \"%[2]v/blog/3\" not accessible; status code 404: Not Found\n", relDirPath, srv.URL), summary.String())
+
+		warnings.Reset()
+		testutil.Equals(t, 0, warnings.Len())
+		testutil.Ok(t, warnings.Err())
+	})
+	t.Run("check links in offline modes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request in offline mode: %v", r.URL)
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "offline.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/cached) [2](%[1]s/cached-404) [3](%[1]s/unknown) [4](%[1]s/cached#yolo) [5](mailto:test@mdox.com) [6](../not-existing.md)\n", srv.URL)), os.ModePerm))
+		filePath := "/repo/docs/test/offline.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+		notExistingErr := fmt.Sprintf("%v:1: link ../not-existing.md, normalized to: %v/repo/docs/not-existing.md: file not found", relDirPath+filePath, tmpDir)
+
+		v, err := NewValidator(context.TODO(), logger, []byte(""), anchorDir, nil, nil, WithMode(ModeLocalOnly))
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%v: %v", tmpDir+filePath, notExistingErr), err.Error())
+
+		_, err = NewValidator(context.TODO(), logger, []byte(""), anchorDir, nil, nil, WithMode(ModeCacheOnly))
+		testutil.NotOk(t, err)
+
+		storage := &cache.MemoryStorage{}
+		v, err = NewValidator(context.TODO(), logger, []byte("version: 1\ncache:\n  type: 'memory'\n  failureValidity:\n    '404': '1h'\n"), anchorDir, storage, nil, WithMode(ModeCacheOnly))
+		testutil.Ok(t, err)
+		testutil.Ok(t, storage.CacheResult(srv.URL+"/cached", cache.Result{StatusCode: http.StatusOK}))
+		testutil.Ok(t, storage.CacheResult(srv.URL+"/cached-404", cache.Result{StatusCode: http.StatusNotFound, ErrorClass: cache.ErrorClassStatus}))
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		// Unknown links and fragments not cached are unverified, but do not fail.
+		merr := err.Error()
+		testutil.Assert(t, strings.Contains(merr, notExistingErr), merr)
+		testutil.Assert(t, strings.Contains(merr, fmt.Sprintf("%v:1: \"%v/cached-404\" not accessible (cached failure from", relDirPath+filePath, srv.URL)), merr)
+		testutil.Assert(t, !strings.Contains(merr, "unknown"), merr)
+		testutil.Assert(t, !strings.Contains(merr, "yolo"), merr)
+	})
+	t.Run("check links to local repository", func(t *testing.T) {
+		testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "repo", "docs", "test", "lr-target.md"), []byte("# Yolo\n\nline 3\n"), os.ModePerm))
+		testutil.Ok(t, os.MkdirAll(filepath.Join(tmpDir, "sibling"), os.ModePerm))
+		testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "sibling", "README.md"), []byte("# Sibling\n"), os.ModePerm))
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "local-repo.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](https://github.com/bwplotka/mdox/blob/main/docs/test/lr-target.md#yolo)
+[2](https://github.com/bwplotka/mdox/tree/main/docs/test)
+[3](https://github.com/bwplotka/mdox/blob/main/docs/test/lr-target.md#L2-L3)
+[4](https://github.com/bwplotka/mdox/blob/main/docs/test/lr-target.md#L10)
+[5](https://github.com/bwplotka/mdox/blob/main/docs/test/missing.md)
+[6](https://github.com/bwplotka/mdox/blob/v0.1.0/docs/test/missing.md)
+[7](https://gitlab.com/org/sibling/-/blob/main/README.md#sibling)
+[8](https://gitlab.com/org/sibling/-/blob/main/README.md#yolo)
+`), os.ModePerm))
+		filePath := "/repo/docs/test/local-repo.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		// Links to other refs are remote, so they are skipped without network access.
+		v, err := NewValidator(context.TODO(), logger, []byte(`version: 1
+validators:
+  - type: 'localRepo'
+    repoURL: 'https://github.com/bwplotka/mdox'
+    localDir: '..'
+  - type: 'localRepo'
+    repoURL: 'https://gitlab.com/org/sibling/'
+    localDir: '../../sibling'
+`), anchorDir, nil, nil, WithMode(ModeLocalOnly))
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%[1]v: 3 errors: "+
+			"%[2]v:8: link https://gitlab.com/org/sibling/-/blob/main/README.md#yolo, normalized to: link %[3]v/sibling/README.md#yolo, existing ids: [sibling]: file exists, but does not have such id; "+
+			"%[2]v:5: link https://github.com/bwplotka/mdox/blob/main/docs/test/missing.md, normalized to: %[3]v/repo/docs/test/missing.md: file not found; "+
+			"%[2]v:4: link https://github.com/bwplotka/mdox/blob/main/docs/test/lr-target.md#L10, normalized to: %[3]v/repo/docs/test/lr-target.md#L10: file has 3 lines: file exists, but does not have such id",
+			tmpDir+filePath, relDirPath+filePath, tmpDir), err.Error())
+	})
+	t.Run("check github links precisely", func(t *testing.T) {
+		var queries int
+		aliasRe := regexp.MustCompile(`n(\d+): issueOrPullRequest`)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer gh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			queries++
+			var req struct {
+				Query     string                 `json:"query"`
+				Variables map[string]interface{} `json:"variables"`
+			}
+			testutil.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+			testutil.Equals(t, "bwplotka", req.Variables["owner"])
+			testutil.Equals(t, "mdox", req.Variables["name"])
+
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := req.Variables["num"]; ok {
+				// Comments of issue 1, in two pages.
+				if req.Variables["cursor"] == nil {
+					_, _ = w.Write([]byte(`{"data":{"repository":{"issueOrPullRequest":{"comments":{"nodes":[{"databaseId":10}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":{"repository":{"issueOrPullRequest":{"comments":{"nodes":[{"databaseId":12}],"pageInfo":{"hasNextPage":false,"endCursor":"c2"}}}}}}`))
+				return
+			}
+			types := map[string]string{"1": `{"__typename":"Issue"}`, "2": `{"__typename":"PullRequest"}`, "4": `{"__typename":"Issue"}`}
+			var nodes []string
+			for _, m := range aliasRe.FindAllStringSubmatch(req.Query, -1) {
+				typ, ok := types[m[1]]
+				if !ok {
+					typ = "null"
+				}
+				nodes = append(nodes, fmt.Sprintf(`"n%v":%v`, m[1], typ))
+			}
+			_, _ = w.Write([]byte(`{"data":{"repository":{` + strings.Join(nodes, ",") + `}},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to an issue or pull request with the number of 3."}]}`))
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "github-precise.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](https://github.com/bwplotka/mdox/issues/1)
+[2](https://github.com/bwplotka/mdox/pull/2/files)
+[3](https://github.com/bwplotka/mdox/issues/3)
+[4](https://github.com/bwplotka/mdox/pull/4)
+[5](https://github.com/bwplotka/mdox/issues/1#issuecomment-12)
+[6](https://github.com/bwplotka/mdox/issues/1#issuecomment-11)
+[7](https://github.com/bwplotka/mdox/issues/2)
+`), os.ModePerm))
+		filePath := "/repo/docs/test/github-precise.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		config := []byte(fmt.Sprintf(`version: 1
+cache:
+  type: 'file'
+  failureValidity:
+    '404': '1h'
+validators:
+  - type: 'githubPullsIssues'
+    regex: '(^http[s]?:\/\/)(www\.)?(github\.com\/)bwplotka\/mdox(\/pull\/|\/issues\/)'
+    precise: true
+    apiURL: '%v'
+    token: 'gh-token'
+`, srv.URL))
+		cachePath := filepath.Join(tmpDir, "github-precise-cache")
+		storage := cache.NewStorage(cachePath, false)
+		v, err := NewValidator(context.TODO(), logger, config, anchorDir, storage, nil)
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.Ok(t, storage.Close())
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%[1]v: 4 errors: "+
+			"%[2]v:4: link https://github.com/bwplotka/mdox/pull/4, bwplotka/mdox#4 is an issue, not a pull request; "+
+			"%[2]v:3: link https://github.com/bwplotka/mdox/issues/3, issue or pull request bwplotka/mdox#3 does not exist; "+
+			"%[2]v:7: link https://github.com/bwplotka/mdox/issues/2, bwplotka/mdox#2 is a pull request, not an issue; "+
+			"%[2]v:6: link https://github.com/bwplotka/mdox/issues/1#issuecomment-11, comment 11 of bwplotka/mdox#1 does not exist",
+			tmpDir+filePath, relDirPath+filePath), err.Error())
+		// All numbers in single query and two pages of comments.
+		testutil.Equals(t, 3, queries)
+
+		// Results are cached, so API is not queried again.
+		storage = cache.NewStorage(cachePath, false)
+		v, err = NewValidator(context.TODO(), logger, config, anchorDir, storage, nil)
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.Ok(t, storage.Close())
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.HasPrefix(err.Error(), tmpDir+filePath+": 4 errors: "), err.Error())
+		testutil.Equals(t, 3, queries)
+
+		// The same cached results are used in cache-only mode.
+		statuses := map[string]LinkStatus{}
+		storage = cache.NewStorage(cachePath, false)
+		v, err = NewValidator(context.TODO(), logger, config, anchorDir, storage, nil, WithMode(ModeCacheOnly), WithResultFn(func(r LinkResult) {
+			statuses[r.Destination] = r.Status
+		}))
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.Ok(t, storage.Close())
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.HasPrefix(err.Error(), tmpDir+filePath+": 4 errors: "), err.Error())
+		testutil.Equals(t, 3, queries)
+		testutil.Equals(t, map[string]LinkStatus{
+			"https://github.com/bwplotka/mdox/issues/1":                 LinkStatusValid,
+			"https://github.com/bwplotka/mdox/pull/2/files":             LinkStatusValid,
+			"https://github.com/bwplotka/mdox/issues/3":                 LinkStatusInvalid,
+			"https://github.com/bwplotka/mdox/pull/4":                   LinkStatusInvalid,
+			"https://github.com/bwplotka/mdox/issues/1#issuecomment-12": LinkStatusValid,
+			"https://github.com/bwplotka/mdox/issues/1#issuecomment-11": LinkStatusInvalid,
+			"https://github.com/bwplotka/mdox/issues/2":                 LinkStatusInvalid,
+		}, statuses)
+	})
+	t.Run("check github links precisely with host timeout", func(t *testing.T) {
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Slower than host timeout.
+			<-done
+		}))
+		t.Cleanup(srv.Close)
+		t.Cleanup(func() { close(done) })
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "github-precise-timeout.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte("[1](https://github.com/bwplotka/mdox/issues/1)\n"), os.ModePerm))
+
+		config := []byte(fmt.Sprintf(`version: 1
+hosts:
+  '%v':
+    timeout: '100ms'
+validators:
+  - type: 'githubPullsIssues'
+    regex: '(^http[s]?:\/\/)(www\.)?(github\.com\/)bwplotka\/mdox(\/pull\/|\/issues\/)'
+    precise: true
+    apiURL: '%v'
+`, strings.TrimPrefix(srv.URL, "http://"), srv.URL))
+		v, err := NewValidator(context.TODO(), logger, config, anchorDir, nil, nil)
+		testutil.Ok(t, err)
+		start := time.Now()
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.Contains(err.Error(), "GitHub GraphQL request: "), err.Error())
+		testutil.Assert(t, time.Since(start) < 5*time.Second, "request was not timed out")
+	})
+	t.Run("check gitlab and gitea issue links", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.URL.EscapedPath() == "/gitlab/projects/org%2Fgroup%2Fproject/issues" && r.Header.Get("PRIVATE-TOKEN") == "gl-token":
+				_, _ = w.Write([]byte(`[{"iid":10}]`))
+			case r.URL.EscapedPath() == "/gitlab/projects/org%2Fgroup%2Fproject/merge_requests" && r.Header.Get("PRIVATE-TOKEN") == "gl-token":
+				_, _ = w.Write([]byte(`[{"iid":5}]`))
+			case r.URL.Path == "/gitea/repos/owner/repo/issues" && r.Header.Get("Authorization") == "token gt-token":
+				if r.URL.Query().Get("type") == "pulls" {
+					_, _ = w.Write([]byte(`[{"number":7}]`))
+					return
+				}
+				_, _ = w.Write([]byte(`[{"number":4}]`))
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "forges.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`[1](https://gitlab.example.com/org/group/project/-/issues/10#note_1)
+[2](https://gitlab.example.com/org/group/project/-/merge_requests/5/diffs)
+[3](https://gitlab.example.com/org/group/project/-/merge_requests/6)
+[4](https://gitea.example.com/owner/repo/issues/7)
+[5](https://gitea.example.com/owner/repo/pulls/8)
+`), os.ModePerm))
+		filePath := "/repo/docs/test/forges.md"
+		wdir, err := os.Getwd()
+		testutil.Ok(t, err)
+		relDirPath, err := filepath.Rel(wdir, tmpDir)
+		testutil.Ok(t, err)
+
+		v, err := NewValidator(context.TODO(), logger, []byte(fmt.Sprintf(`version: 1
+validators:
+  - type: 'gitlabIssuesMergeRequests'
+    regex: '^https:\/\/gitlab\.example\.com\/org\/group\/project\/-\/(issues|merge_requests)\/'
+    repo: 'org/group/project'
+    apiURL: '%[1]v/gitlab'
+    token: 'gl-token'
+  - type: 'giteaPullsIssues'
+    regex: '^https:\/\/gitea\.example\.com\/owner\/repo\/(issues|pulls)\/'
+    repo: 'owner/repo'
+    apiURL: '%[1]v/gitea/'
+    token: 'gt-token'
+`, srv.URL)), anchorDir, nil, nil)
+		testutil.Ok(t, err)
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, fmt.Sprintf("%[1]v: 2 errors: "+
+			"%[2]v:3: link https://gitlab.example.com/org/group/project/-/merge_requests/6, number 6 does not exist, latest is 5; "+
+			"%[2]v:5: link https://gitea.example.com/owner/repo/pulls/8, number 8 does not exist, latest is 7",
+			tmpDir+filePath, relDirPath+filePath), err.Error())
+
+		_, err = NewValidator(context.TODO(), logger, []byte(fmt.Sprintf(`version: 1
+validators:
+  - type: 'giteaPullsIssues'
+    regex: '^https:\/\/gitea\.example\.com\/owner\/repo\/(issues|pulls)\/'
+    repo: 'owner/repo'
+    apiURL: '%v/gitea'
+`, srv.URL)), anchorDir, nil, nil)
+		testutil.NotOk(t, err)
+	})
+	t.Run("check gitlab issue links with host timeout", func(t *testing.T) {
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Slower than host timeout.
+			<-done
+		}))
+		t.Cleanup(srv.Close)
+		t.Cleanup(func() { close(done) })
+
+		start := time.Now()
+		_, err := NewValidator(context.TODO(), logger, []byte(fmt.Sprintf(`version: 1
+hosts:
+  '%v':
+    timeout: '100ms'
+validators:
+  - type: 'gitlabIssuesMergeRequests'
+    regex: '^https:\/\/gitlab\.example\.com\/org\/group\/project\/-\/(issues|merge_requests)\/'
+    repo: 'org/group/project'
+    apiURL: '%v/gitlab'
+`, strings.TrimPrefix(srv.URL, "http://"), srv.URL)), anchorDir, nil, nil)
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.Contains(err.Error(), "context deadline exceeded"), err.Error())
+		testutil.Assert(t, time.Since(start) < 5*time.Second, "request was not timed out")
+	})
+	t.Run("fix permanent redirects", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/old":
+				http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+			case "/older":
+				http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+			case "/temporary":
+				http.Redirect(w, r, "/new", http.StatusFound)
+			case "/new":
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(`<html><body><h2 id="yolo">Yolo</h2></body></html>`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "redirects.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%[1]s/old) [2](%[1]s/old#yolo) [3](%[1]s/temporary)\n", srv.URL)), os.ModePerm))
+
+		v, err := NewValidator(context.TODO(), logger, []byte(""), anchorDir, nil, nil, WithFixRedirects())
+		testutil.Ok(t, err)
+
+		// Redirects are not known before links are checked.
+		diff, err := mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v))
+		testutil.Ok(t, err)
+		testutil.Equals(t, 0, len(diff), diff.String())
+
+		testutil.Ok(t, mdformatter.Format(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(v)))
+		b, err := os.ReadFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, fmt.Sprintf("[1](%[1]s/new) [2](%[1]s/new#yolo) [3](%[1]s/temporary)\n", srv.URL), string(b))
+	})
+	t.Run("check 404 link with failure cache", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "failure-cache.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%s/does-not-exists)\n", srv.URL)), os.ModePerm))
+		cacheFile := filepath.Join(tmpDir, "repo", "docs", "test", "mdoxcachetest-failures")
+		config := []byte("version: 1\n\ncache:\n  type: 'sqlite'\n  failureValidity:\n    '404': '24h'\n")
+
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, config, anchorDir, &cache.SQLite3Storage{Filename: cacheFile}),
+		))
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.Contains(err.Error(), "not accessible; status code 404: Not Found"), err.Error())
+		testutil.Equals(t, 1, requests)
+
+		// Second run uses cached failure.
+		_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+			MustNewValidator(logger, config, anchorDir, &cache.SQLite3Storage{Filename: cacheFile}),
+		))
+		testutil.NotOk(t, err)
+		testutil.Assert(t, strings.Contains(err.Error(), "(cached failure from "), err.Error())
+		testutil.Assert(t, strings.Contains(err.Error(), "status code 404, error class status"), err.Error())
+		testutil.Equals(t, 1, requests)
+	})
+	t.Run("check expired cached link with conditional request", func(t *testing.T) {
+		var full, notModified int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == "Wed, 21 Oct 2015 07:28:00 GMT" {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			full++
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		}))
+		t.Cleanup(srv.Close)
+
+		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "conditional.md")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(fmt.Sprintf("[1](%s/page)\n", srv.URL)), os.ModePerm))
+		cacheFile := filepath.Join(tmpDir, "repo", "docs", "test", "mdoxcachetest-conditional")
+		// Entries expire immediately.
+		config := []byte("version: 1\n\ncache:\n  type: 'sqlite'\n  validity: '1ns'\n")
+
+		for i := 0; i < 3; i++ {
+			_, err = mdformatter.IsFormatted(context.TODO(), logger, []string{testFile}, mdformatter.WithLinkTransformer(
+				MustNewValidator(logger, config, anchorDir, &cache.SQLite3Storage{Filename: cacheFile}),
+			))
+			testutil.Ok(t, err)
+		}
+		testutil.Equals(t, 1, full)
+		testutil.Equals(t, 2, notModified)
+	})
 	t.Run("check 404 links with ignore validate config", func(t *testing.T) {
 		testFile := filepath.Join(tmpDir, "repo", "docs", "test", "links.md")
 		testutil.Ok(t, os.WriteFile(testFile, []byte("https://fakelink1.com/ http://fakelink2.com/ https://www.fakelink3.com/\n"), os.ModePerm))
@@ -0,0 +1,78 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/go-kit/log"
+	"gopkg.in/yaml.v3"
+)
+
+// LocalizeConfig is configuration of link localization, e.g. for multi-version or multi-domain docs.
+type LocalizeConfig struct {
+	Version int
+
+	// Targets map remote addresses to local directories. They are evaluated in order and link is localized with the
+	// first target, which matches it and has such local file.
+	Targets []LocalizeTarget `yaml:"targets"`
+}
+
+type LocalizeTarget struct {
+	// AddressRegex matches domain and path of HTTP(s) links, without scheme e.g. thanos\.io/tip/. The rest of link
+	// after the match is path within Dir.
+	AddressRegex string `yaml:"addressRegex" jsonschema:"required"`
+	// Dir is local directory matched links are localized to, relative to anchor dir e.g. docs/. Anchor dir is used if
+	// empty.
+	Dir string `yaml:"dir"`
+}
+
+type localizeTarget struct {
+	address *regexp.Regexp
+	dir     string
+}
+
+// ParseLocalizeConfig parses link localization configuration.
+func ParseLocalizeConfig(c []byte) (LocalizeConfig, error) {
+	cfg := LocalizeConfig{}
+	dec := yaml.NewDecoder(bytes.NewReader(c))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return LocalizeConfig{}, fmt.Errorf("parsing YAML content: %w", err)
+	}
+	for _, t := range cfg.Targets {
+		if t.AddressRegex == "" {
+			return LocalizeConfig{}, errors.New("addressRegex of localize target is required")
+		}
+	}
+	return cfg, nil
+}
+
+// NewLocalizerFromConfig returns mdformatter.LinkTransformer that transforms links matching address regexp of any
+// configured target to local file path within target directory (if exists), evaluating targets in order.
+func NewLocalizerFromConfig(logger log.Logger, linksLocalizeConfig []byte, anchorDir string) (mdformatter.LinkTransformer, error) {
+	cfg, err := ParseLocalizeConfig(linksLocalizeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]localizeTarget, 0, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		re, err := regexp.Compile(t.AddressRegex)
+		if err != nil {
+			return nil, fmt.Errorf("parsing address regex of localize target %v: %w", t.AddressRegex, err)
+		}
+		dir := t.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(anchorDir, dir)
+		}
+		targets = append(targets, localizeTarget{address: re, dir: dir})
+	}
+	return &localizer{logger: logger, targets: targets, anchorDir: anchorDir, localLinksByFile: map[string]*[]string{}}, nil
+}
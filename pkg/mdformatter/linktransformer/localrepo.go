@@ -0,0 +1,165 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var defaultLocalRepoRefs = []string{"main", "master"}
+
+// lineAnchorRe matches GitHub and GitLab line anchors e.g. L10, L10-L20 or L10C5-L20C1 and GitLab L10-20.
+var lineAnchorRe = regexp.MustCompile(`^L(\d+)(?:C\d+)?(?:-L?(\d+)(?:C\d+)?)?$`)
+
+func newLocalRepoValidator(c ValidatorConfig) (LocalRepoValidator, error) {
+	if c.RepoURL == "" {
+		return LocalRepoValidator{}, errors.New("repoURL is required")
+	}
+	repoURL := strings.TrimSuffix(c.RepoURL, "/")
+	if !remoteLinkPrefixRe.MatchString(repoURL) {
+		return LocalRepoValidator{}, fmt.Errorf("repoURL %v has to be HTTP(s) URL", c.RepoURL)
+	}
+
+	regex := c.Regex
+	if regex == "" {
+		// GitLab prefixes blob and tree with "/-".
+		regex = "^" + regexp.QuoteMeta(repoURL) + `(/-)?/(blob|tree)/`
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return LocalRepoValidator{}, err
+	}
+
+	refs := c.Refs
+	if len(refs) == 0 {
+		refs = defaultLocalRepoRefs
+	}
+	return LocalRepoValidator{_regex: re, _repoURL: repoURL, _localDir: c.LocalDir, _refs: refs}, nil
+}
+
+// LocalRepoValidator.IsValid checks blob and tree links of repository against its local checkout, including heading
+// and line anchors. Links to other refs are checked as remote links.
+func (v LocalRepoValidator) IsValid(k futureKey, r *validator) (bool, error) {
+	path, fragment, ok := v.localPath(k.dest)
+	if !ok {
+		if r.mode != ModeFull {
+			r.checkOffline(k, nil)
+			return true, nil
+		}
+		return RoundTripValidator{}.IsValid(k, r)
+	}
+
+	r.l.localLinksChecked.Inc()
+	dir := v._localDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.anchorDir, dir)
+	}
+	absPath := filepath.Join(dir, filepath.FromSlash(path))
+
+	var err error
+	switch {
+	case fragment == "":
+		err = r.localLinks.Lookup(r.anchorDir, absPath)
+	case lineAnchorRe.MatchString(fragment):
+		err = r.checkLines(absPath, fragment)
+	default:
+		err = r.localLinks.Lookup(r.anchorDir, absPath+"#"+fragment)
+	}
+	if err != nil {
+		r.destFutures[k].resultFn = func() error { return fmt.Errorf("link %v, normalized to: %w", k.dest, err) }
+		return false, nil
+	}
+	return true, nil
+}
+
+// localPath returns path within repository and fragment of blob or tree link. It returns false if link is not to one of
+// the checked refs.
+func (v LocalRepoValidator) localPath(dest string) (path string, fragment string, _ bool) {
+	if !strings.HasPrefix(dest, v._repoURL+"/") {
+		return "", "", false
+	}
+	rest, fragment := splitFragment(strings.TrimPrefix(dest, v._repoURL+"/"))
+	rest = strings.SplitN(rest, "?", 2)[0]
+	rest = strings.TrimPrefix(rest, "-/")
+
+	// <blob|tree>/<ref>/<path>. Refs with slashes are not supported, as they can't be told apart from path.
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 || (parts[0] != "blob" && parts[0] != "tree") || !containsString(v._refs, parts[1]) {
+		return "", "", false
+	}
+	if len(parts) == 3 {
+		path = strings.TrimSuffix(parts[2], "/")
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	return path, fragment, true
+}
+
+// checkLines checks if file has lines referenced by line anchor.
+// NOTE: futureMu has to be held by the caller.
+func (v *validator) checkLines(absPath string, fragment string) error {
+	lines, ok := v.localLineCounts[absPath]
+	if !ok {
+		if !hasExactCase(v.anchorDir, absPath) {
+			// Missing or found only thanks to case-insensitive file system.
+			return fileNotFoundErr(v.anchorDir, absPath)
+		}
+		var err error
+		if lines, err = countLines(absPath); err != nil {
+			if os.IsNotExist(err) {
+				return fileNotFoundErr(v.anchorDir, absPath)
+			}
+			return err
+		}
+		v.localLineCounts[absPath] = lines
+	}
+
+	for _, l := range lineAnchorRe.FindStringSubmatch(fragment)[1:] {
+		if l == "" {
+			continue
+		}
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			return err
+		}
+		if n < 1 || n > lines {
+			return fmt.Errorf("%v#%v: file has %v lines: %w", absPath, fragment, lines, IDNotFoundErr)
+		}
+	}
+	return nil
+}
+
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	lines := 0
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for s.Scan() {
+		lines++
+	}
+	return lines, s.Err()
+}
+
+func containsString(s []string, e string) bool {
+	for _, i := range s {
+		if i == e {
+			return true
+		}
+	}
+	return false
+}
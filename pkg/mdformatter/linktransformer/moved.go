@@ -0,0 +1,214 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/efficientgo/core/merrors"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+type movedFixer struct {
+	logger    log.Logger
+	anchorDir string
+	// repoDir is the top level directory of git repository.
+	repoDir string
+	// renames are new paths of renamed files by old path, relative to repoDir.
+	renames map[string][]string
+
+	// ambiguous holds links moved to multiple locations by file.
+	ambiguous map[string][]ambiguousLink
+}
+
+type ambiguousLink struct {
+	dest, lineNumbers string
+	targets           []string
+}
+
+// NewMovedFixer returns mdformatter.LinkTransformer that rewrites local links to not existing files or directories, if
+// git history of repository with anchorDir knows where they were moved. Links moved to multiple locations are not
+// rewritten, but reported as errors.
+func NewMovedFixer(ctx context.Context, logger log.Logger, anchorDir string) (mdformatter.LinkTransformer, error) {
+	// Relative path to top level directory is used, so paths match also if anchorDir is behind symlink.
+	out, err := git(ctx, anchorDir, "rev-parse", "--show-cdup")
+	if err != nil {
+		return nil, fmt.Errorf("find git repository of %v: %w", anchorDir, err)
+	}
+	repoDir := filepath.Join(anchorDir, strings.TrimSpace(string(out)))
+
+	// Rename detection is done for each commit, so renames with changes are detected too.
+	out, err = git(ctx, repoDir, "log", "-M", "--diff-filter=R", "--name-status", "--format=", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("list renames in git history: %w", err)
+	}
+	return &movedFixer{
+		logger:    logger,
+		anchorDir: anchorDir,
+		repoDir:   repoDir,
+		renames:   parseRenames(out),
+		ambiguous: map[string][]ambiguousLink{},
+	}, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %v: %w: %v", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parseRenames parses NUL separated `git log --name-status -z` output of renames e.g. "R100\x00old\x00new\x00".
+func parseRenames(out []byte) map[string][]string {
+	renames := map[string][]string{}
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i+2 < len(fields); i++ {
+		if !strings.HasPrefix(strings.TrimSpace(fields[i]), "R") {
+			continue
+		}
+		from, to := fields[i+1], fields[i+2]
+		i += 2
+		if !containsString(renames[from], to) {
+			renames[from] = append(renames[from], to)
+		}
+	}
+	return renames
+}
+
+func (f *movedFixer) TransformDestination(ctx mdformatter.SourceContext, destination []byte) (_ []byte, err error) {
+	dest := string(destination)
+	if remoteLinkPrefixRe.MatchString(dest) || strings.HasPrefix(dest, "#") || strings.Contains(dest, ":") {
+		return destination, nil
+	}
+
+	absLink := absLocalLink(f.anchorDir, ctx.Filepath, dest)
+	absPath, fragment := splitFragment(absLink)
+	if _, err := os.Stat(absPath); err == nil || !os.IsNotExist(err) {
+		return destination, nil
+	}
+	rel, err := filepath.Rel(f.repoDir, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return destination, nil
+	}
+
+	targets := f.resolve(filepath.ToSlash(rel))
+	switch len(targets) {
+	case 0:
+		return destination, nil
+	case 1:
+	default:
+		f.ambiguous[ctx.Filepath] = append(f.ambiguous[ctx.Filepath], ambiguousLink{dest: dest, lineNumbers: ctx.LineNumbers, targets: targets})
+		return destination, nil
+	}
+
+	newAbsPath := filepath.Join(f.repoDir, filepath.FromSlash(targets[0]))
+	var newDest string
+	if filepath.IsAbs(dest) {
+		// Keep links relative to anchor dir as such.
+		newRel, err := filepath.Rel(f.anchorDir, newAbsPath)
+		if err != nil || strings.HasPrefix(newRel, "..") {
+			return destination, nil
+		}
+		newDest = "/" + filepath.ToSlash(newRel)
+	} else {
+		newRel, err := filepath.Rel(filepath.Dir(ctx.Filepath), newAbsPath)
+		if err != nil {
+			return destination, nil
+		}
+		newDest = filepath.ToSlash(newRel)
+	}
+	if fragment != "" {
+		newDest += "#" + fragment
+	}
+	level.Info(f.logger).Log("msg", "fixing link to moved file", "file", ctx.Filepath, "link", dest, "target", newDest)
+	return []byte(newDest), nil
+}
+
+// resolve returns existing paths, where given path was moved to, following subsequent renames. Directories are
+// resolved by renames of files within them.
+func (f *movedFixer) resolve(p string) []string {
+	visited := map[string]struct{}{}
+	var targets []string
+	var walk func(p string)
+	walk = func(p string) {
+		if _, ok := visited[p]; ok {
+			return
+		}
+		visited[p] = struct{}{}
+		if _, err := os.Stat(filepath.Join(f.repoDir, filepath.FromSlash(p))); err == nil {
+			if !containsString(targets, p) {
+				targets = append(targets, p)
+			}
+			return
+		}
+		news, ok := f.renames[p]
+		if !ok {
+			news = f.movedDirs(p)
+		}
+		for _, n := range news {
+			walk(n)
+		}
+	}
+	walk(p)
+	sort.Strings(targets)
+	return targets
+}
+
+// movedDirs returns new locations of directory, based on renames of files within it, keeping their relative path.
+func (f *movedFixer) movedDirs(dir string) []string {
+	var dirs []string
+	prefix := dir + "/"
+	for from, tos := range f.renames {
+		if !strings.HasPrefix(from, prefix) {
+			continue
+		}
+		suffix := strings.TrimPrefix(from, dir)
+		for _, to := range tos {
+			if !strings.HasSuffix(to, suffix) {
+				// File was renamed too, so new directory can't be told.
+				continue
+			}
+			if d := path.Clean(strings.TrimSuffix(to, suffix)); !containsString(dirs, d) {
+				dirs = append(dirs, d)
+			}
+		}
+	}
+	return dirs
+}
+
+func (f *movedFixer) Close(ctx mdformatter.SourceContext) error {
+	links := f.ambiguous[ctx.Filepath]
+	delete(f.ambiguous, ctx.Filepath)
+	if len(links) == 0 {
+		return nil
+	}
+
+	base, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("resolve working dir: %w", err)
+	}
+	p, err := filepath.Rel(base, ctx.Filepath)
+	if err != nil {
+		return fmt.Errorf("find relative path: %w", err)
+	}
+	errs := merrors.New()
+	for _, l := range links {
+		errs.Add(fmt.Errorf("%v:%v: link %v, target moved to multiple locations: %v", p, l.lineNumbers, l.dest, strings.Join(l.targets, ", ")))
+	}
+	return errs.Err()
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/bwplotka/mdox/pkg/mdformatter"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"gopkg.in/yaml.v3"
+)
+
+// RewriteConfig is configuration of link rewrites, e.g. for domain migrations.
+type RewriteConfig struct {
+	Version int
+
+	// Rewrites are applied in order to each link, so later rules see links rewritten by earlier ones.
+	Rewrites []RewriteRule `yaml:"rewrites"`
+}
+
+type RewriteRule struct {
+	// Regex matching links to rewrite e.g. ^https://old\.example\.io/(.*)$.
+	Regex string `yaml:"regex"`
+	// Replacement of all Regex matches in link. It can reference capture groups e.g. https://docs.example.com/$1 or
+	// ${name} for named groups.
+	Replacement string `yaml:"replacement"`
+
+	_regex *regexp.Regexp
+}
+
+// ParseRewriteConfig parses link rewrites configuration.
+func ParseRewriteConfig(c []byte) (RewriteConfig, error) {
+	cfg := RewriteConfig{}
+	dec := yaml.NewDecoder(bytes.NewReader(c))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return RewriteConfig{}, fmt.Errorf("parsing YAML content: %w", err)
+	}
+
+	for i := range cfg.Rewrites {
+		if cfg.Rewrites[i].Regex == "" {
+			return RewriteConfig{}, errors.New("regex of rewrite is required")
+		}
+		re, err := regexp.Compile(cfg.Rewrites[i].Regex)
+		if err != nil {
+			return RewriteConfig{}, fmt.Errorf("parsing regex of rewrite %v: %w", cfg.Rewrites[i].Regex, err)
+		}
+		cfg.Rewrites[i]._regex = re
+	}
+	return cfg, nil
+}
+
+type rewriter struct {
+	logger   log.Logger
+	rewrites []RewriteRule
+}
+
+// NewRewriter returns mdformatter.LinkTransformer that rewrites links matching rewrite rules of given configuration.
+// Every rewrite is logged.
+func NewRewriter(logger log.Logger, linksRewriteConfig []byte) (mdformatter.LinkTransformer, error) {
+	cfg, err := ParseRewriteConfig(linksRewriteConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriter{logger: logger, rewrites: cfg.Rewrites}, nil
+}
+
+func (r *rewriter) TransformDestination(ctx mdformatter.SourceContext, destination []byte) (_ []byte, err error) {
+	dest := string(destination)
+	for _, rw := range r.rewrites {
+		dest = rw._regex.ReplaceAllString(dest, rw.Replacement)
+	}
+	if dest == string(destination) {
+		return destination, nil
+	}
+	level.Info(r.logger).Log("msg", "rewriting link", "file", fmt.Sprintf("%v:%v", ctx.Filepath, ctx.LineNumbers), "link", string(destination), "target", dest)
+	return []byte(dest), nil
+}
+
+func (r *rewriter) Close(mdformatter.SourceContext) error { return nil }
@@ -0,0 +1,180 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxListedIDs is the maximum number of existing ids listed in error, when there is no suggestion.
+const maxListedIDs = 5
+
+// fileNotFoundErr returns error for not existing local path, naming the closest existing path below baseDir, if any.
+func fileNotFoundErr(baseDir string, absPath string) error {
+	suggestion, caseOnly := suggestPath(baseDir, absPath)
+	switch {
+	case suggestion == "":
+		return fmt.Errorf("%v: %w", absPath, FileNotFoundErr)
+	case caseOnly:
+		name, existing := firstCaseMismatch(absPath, suggestion)
+		return fmt.Errorf("%v: %w (path element %v differs only in case from %v of %v, which works on case-insensitive file systems only)", absPath, FileNotFoundErr, name, existing, suggestion)
+	default:
+		return fmt.Errorf("%v: %w (did you mean %v?)", absPath, FileNotFoundErr, suggestion)
+	}
+}
+
+// idNotFoundErr returns error for not existing id of local file, naming the closest existing id, if any.
+func idNotFoundErr(absLink string, id string, ids []string) error {
+	suggestion, caseOnly := closest(id, ids)
+	switch {
+	case suggestion != "" && caseOnly:
+		return fmt.Errorf("link %v, id differs only in case from #%v: %w", absLink, suggestion, IDNotFoundErr)
+	case suggestion != "":
+		return fmt.Errorf("link %v, did you mean #%v?: %w", absLink, suggestion, IDNotFoundErr)
+	case len(ids) > maxListedIDs:
+		return fmt.Errorf("link %v, %v existing ids: %w", absLink, len(ids), IDNotFoundErr)
+	default:
+		return fmt.Errorf("link %v, existing ids: %v: %w", absLink, ids, IDNotFoundErr)
+	}
+}
+
+// suggestPath returns the closest existing path to not existing one, fixing each path element below baseDir, which
+// is not on disk with exact case, with the closest sibling. It returns true if path differs only in case.
+func suggestPath(baseDir string, absPath string) (_ string, caseOnly bool) {
+	dir, elems := relElements(baseDir, absPath)
+	caseOnly, fixed := true, false
+	for _, name := range elems {
+		names, err := dirNames(dir)
+		if err != nil {
+			return "", false
+		}
+		if !containsString(names, name) {
+			existing, nameCaseOnly := closest(name, names)
+			if existing == "" {
+				return "", false
+			}
+			name, caseOnly, fixed = existing, caseOnly && nameCaseOnly, true
+		}
+		dir = filepath.Join(dir, name)
+	}
+	if !fixed {
+		return "", false
+	}
+	return dir, caseOnly
+}
+
+// hasExactCase returns true if each path element of existing path below baseDir has the same case as on disk. On
+// case-insensitive file systems path with different case exists too, but breaks elsewhere.
+func hasExactCase(baseDir string, absPath string) bool {
+	dir, elems := relElements(baseDir, absPath)
+	for _, name := range elems {
+		names, err := dirNames(dir)
+		if err != nil {
+			return true
+		}
+		if !containsString(names, name) {
+			return false
+		}
+		dir = filepath.Join(dir, name)
+	}
+	return true
+}
+
+// relElements returns path elements of absPath below baseDir and the directory they start from. Leading ".." elements
+// are resolved, as parents of baseDir are not checked.
+func relElements(baseDir string, absPath string) (dir string, elems []string) {
+	rel, err := filepath.Rel(baseDir, absPath)
+	if err != nil {
+		return filepath.Dir(absPath), []string{filepath.Base(absPath)}
+	}
+	dir = baseDir
+	for _, e := range strings.Split(rel, string(filepath.Separator)) {
+		switch {
+		case e == ".":
+		case e == ".." && len(elems) == 0:
+			dir = filepath.Dir(dir)
+		default:
+			elems = append(elems, e)
+		}
+	}
+	return dir, elems
+}
+
+// dirNames returns names of entries of dir.
+func dirNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+// firstCaseMismatch returns the first path element of a, which differs only in case from the one of b, and the latter.
+func firstCaseMismatch(a string, b string) (string, string) {
+	ae, be := strings.Split(a, string(filepath.Separator)), strings.Split(b, string(filepath.Separator))
+	for i := 0; i < len(ae) && i < len(be); i++ {
+		if ae[i] != be[i] {
+			return ae[i], be[i]
+		}
+	}
+	return filepath.Base(a), filepath.Base(b)
+}
+
+// closest returns candidate closest to s by edit distance, if it's close enough. Candidates equal to s ignoring case
+// are preferred and reported with true.
+func closest(s string, candidates []string) (_ string, caseOnly bool) {
+	for _, c := range candidates {
+		if c != s && strings.EqualFold(c, s) {
+			return c, true
+		}
+	}
+
+	// Allow roughly one typo per three characters, so short names don't match anything.
+	maxDistance := len([]rune(s)) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	best, bestDistance := "", maxDistance+1
+	for _, c := range candidates {
+		if d := editDistance(s, c); d < bestDistance || (d == bestDistance && best != "" && c < best) {
+			best, bestDistance = c, d
+		}
+	}
+	return best, false
+}
+
+// editDistance returns Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package linktransformer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestSuggestPath(t *testing.T) {
+	base := t.TempDir()
+	testutil.Ok(t, os.MkdirAll(filepath.Join(base, "docs", "guides"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(base, "docs", "guides", "install.md"), []byte("# Install\n"), os.ModePerm))
+	exp := filepath.Join(base, "docs", "guides", "install.md")
+
+	for _, tcase := range []struct {
+		path      string
+		exactCase bool
+		exp       string
+		caseOnly  bool
+	}{
+		{path: "docs/guides/install.md", exactCase: true},
+		// Directory differs only in case, which exists on case-insensitive file systems too.
+		{path: "Docs/guides/install.md", exp: exp, caseOnly: true},
+		{path: "docs/Guides/Install.md", exp: exp, caseOnly: true},
+		{path: "Docs/guide/install.md", exp: exp},
+		{path: "docs/guides/instal.md", exp: exp},
+		{path: "other/guides/install.md"},
+	} {
+		t.Run(tcase.path, func(t *testing.T) {
+			absPath := filepath.Join(base, tcase.path)
+			testutil.Equals(t, tcase.exactCase, hasExactCase(base, absPath))
+
+			suggestion, caseOnly := suggestPath(base, absPath)
+			testutil.Equals(t, tcase.exp, suggestion)
+			testutil.Equals(t, tcase.caseOnly, caseOnly)
+		})
+	}
+
+	// Links outside of base dir are checked below their common parent.
+	testutil.Equals(t, true, hasExactCase(filepath.Join(base, "docs", "guides"), filepath.Join(base, "docs")))
+	testutil.Equals(t, false, hasExactCase(filepath.Join(base, "docs", "guides"), filepath.Join(base, "Docs")))
+
+	err := fileNotFoundErr(base, filepath.Join(base, "Docs", "guides", "install.md"))
+	testutil.Equals(t, filepath.Join(base, "Docs", "guides", "install.md")+": file not found (path element Docs differs only in case from docs of "+exp+", which works on case-insensitive file systems only)", err.Error())
+}
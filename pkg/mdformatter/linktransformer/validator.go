@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/bwplotka/mdox/pkg/cache"
 )
 
 type Validator interface {
@@ -16,6 +18,9 @@ type Validator interface {
 // GitHubPullsIssuesValidator.IsValid skips visiting all GitHub issue/PR links.
 func (v GitHubPullsIssuesValidator) IsValid(k futureKey, r *validator) (bool, error) {
 	r.l.githubSkippedLinks.Inc()
+	if v._graphql != nil {
+		return v.checkPrecise(k, r)
+	}
 	// Find rightmost index of match i.e, where regex match ends.
 	// This will be where issue/PR number starts. Split incase of section link and convert to int.
 	rightmostIndex := v._regex.FindStringIndex(k.dest)
@@ -31,7 +36,8 @@ func (v GitHubPullsIssuesValidator) IsValid(k futureKey, r *validator) (bool, er
 	return false, nil
 }
 
-// RoundTripValidator.IsValid returns true if URL is checked by colly or it is a valid local link.
+// RoundTripValidator.IsValid returns true if URL is checked by colly or it is a valid local link. For URLs with fragment,
+// colly also checks if the page has element with such id.
 func (v RoundTripValidator) IsValid(k futureKey, r *validator) (bool, error) {
 	matches := remoteLinkPrefixRe.FindAllStringIndex(k.dest, 1)
 	if matches == nil && r.validateConfig.ExplicitLocalValidators {
@@ -39,10 +45,13 @@ func (v RoundTripValidator) IsValid(k futureKey, r *validator) (bool, error) {
 		return r.checkLocal(k), nil
 	}
 
-	// Result will be in future.
-	r.destFutures[k].resultFn = func() error { return r.remoteLinks[k.dest] }
+	// Result will be in future. Pages are fetched once for all links to them, regardless of fragment.
+	r.destFutures[k].resultFn = func() error { return r.remoteResult(k.dest) }
+	page, fragment := splitFragment(k.dest)
+	// Body is needed only to check fragment.
+	needBody := fragment != "" && !unverifiableFragmentRe.MatchString(fragment)
 	r.rMu.RLock()
-	if _, ok := r.remoteLinks[k.dest]; ok {
+	if withBody, ok := r.remoteVisits[page]; ok && (withBody || !needBody) {
 		r.rMu.RUnlock()
 		return true, nil
 	}
@@ -51,22 +60,34 @@ func (v RoundTripValidator) IsValid(k futureKey, r *validator) (bool, error) {
 	r.rMu.Lock()
 	defer r.rMu.Unlock()
 	// We need to check again here to avoid race.
-	if _, ok := r.remoteLinks[k.dest]; ok {
+	if withBody, ok := r.remoteVisits[page]; ok && (withBody || !needBody) {
 		return true, nil
 	}
 
-	if r.storage != nil {
+	var expired *cache.Entry
+	if r.storage != nil && !r.fixRedirects {
 		// Check if URL is already in cache database.
-		if ok, err := r.storage.IsCached(k.dest); ok && err == nil {
+		if e, fresh, err := r.storage.Lookup(k.dest); err == nil && fresh && e.ErrorClass == cache.ErrorClassNone {
 			r.l.roundTripCachedLinks.Inc()
 			return true, nil
 		}
+		e, fresh, err := r.storage.Lookup(page)
+		switch {
+		case err != nil || e == nil:
+		case fresh && e.ErrorClass != cache.ErrorClassNone:
+			// Page is known to be broken.
+			r.l.roundTripCachedLinks.Inc()
+			r.remoteVisits[page] = true
+			r.remoteLinks[page] = cachedFailureErr(page, e)
+			return false, nil
+		case !fresh && e.ErrorClass == cache.ErrorClassNone:
+			expired = e
+		}
 	}
 
 	r.l.roundTripVisitedLinks.Inc()
-	r.c.WithTransport(r.transportFn(k.dest))
-	if err := r.c.Visit(k.dest); err != nil {
-		r.remoteLinks[k.dest] = fmt.Errorf("remote link %v: %w", k.dest, err)
+	if err := r.visitRemote(page, needBody, v._acceptStatusCodes, expired); err != nil {
+		r.remoteLinks[page] = fmt.Errorf("remote link %v: %w", page, err)
 		return false, nil
 	}
 	return true, nil
@@ -75,6 +96,7 @@ func (v RoundTripValidator) IsValid(k futureKey, r *validator) (bool, error) {
 // IgnoreValidator.IsValid returns true if matched so that link in not checked.
 func (v IgnoreValidator) IsValid(k futureKey, r *validator) (bool, error) {
 	r.l.ignoreSkippedLinks.Inc()
+	r.destFutures[k].skipped = true
 
 	return true, nil
 }
@@ -98,6 +120,21 @@ func (v Config) GetValidatorForURL(URL string) Validator {
 				continue
 			}
 			return val.igValidator
+		case localRepoValidator:
+			if !val.lrValidator._regex.MatchString(URL) {
+				continue
+			}
+			return val.lrValidator
+		case gitlabValidator:
+			if !val.glValidator._regex.MatchString(URL) {
+				continue
+			}
+			return val.glValidator
+		case giteaValidator:
+			if !val.gtValidator._regex.MatchString(URL) {
+				continue
+			}
+			return val.gtValidator
 		default:
 			panic("unexpected validator type")
 		}
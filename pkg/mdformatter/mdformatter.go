@@ -54,6 +54,8 @@ type SourceContext struct {
 
 	Filepath    string
 	LineNumbers string
+	// LinkText is text of transformed link e.g. alt text of image. It's empty for links in HTML.
+	LinkText string
 }
 
 type FrontMatterTransformer interface {
@@ -76,6 +78,14 @@ type CodeBlockTransformer interface {
 	Close(ctx SourceContext) error
 }
 
+// GenSectionTransformer generates markdown for sections opened with `<!-- mdox-gen-... -->` and closed
+// with `<!-- mdox-gen-end -->` HTML comments. Returned content replaces everything in between,
+// nil means section is left untouched.
+type GenSectionTransformer interface {
+	TransformGenSection(ctx SourceContext, directive []byte) ([]byte, error)
+	Close(ctx SourceContext) error
+}
+
 type Formatter struct {
 	ctx context.Context
 
@@ -83,6 +93,7 @@ type Formatter struct {
 	bm   BackMatterTransformer
 	link LinkTransformer
 	cb   CodeBlockTransformer
+	gs   GenSectionTransformer
 	reg  *prometheus.Registry
 
 	softWraps bool
@@ -120,6 +131,13 @@ func WithCodeBlockTransformer(cb CodeBlockTransformer) Option {
 	}
 }
 
+// WithGenSectionTransformer allows you to override the default GenSectionTransformer.
+func WithGenSectionTransformer(gs GenSectionTransformer) Option {
+	return func(m *Formatter) {
+		m.gs = gs
+	}
+}
+
 // WithMetrics allows you to pass in Prometheus registry.
 func WithMetrics(reg *prometheus.Registry) Option {
 	return func(m *Formatter) {
@@ -285,6 +303,29 @@ func IsFormatted(ctx context.Context, logger log.Logger, files []string, opts ..
 	return d, nil
 }
 
+// FormatInto formats given markdown file and writes the result into output file, instead of in-place. Links are
+// transformed relative to the given file.
+func FormatInto(ctx context.Context, logger log.Logger, file string, output string, opts ...Option) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("open %v: %w", file, err)
+	}
+	defer logerrcapture.ExhaustClose(logger, f, "close file %v", file)
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	b := bytes.Buffer{}
+	if err := New(ctx, opts...).Format(f, &b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, b.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write %v: %w", output, err)
+	}
+	return nil
+}
+
 func format(ctx context.Context, logger log.Logger, files []string, diffs *Diffs, spin *yacspin.Spinner, opts ...Option) error {
 	f := New(ctx, opts...)
 	b := bytes.Buffer{}
@@ -407,11 +448,16 @@ func (f *Formatter) Format(file *os.File, out io.Writer) error {
 	if f.codeFmt {
 		renderer.AddMarkdownOptions(markdown.WithCodeFormatters(markdown.GoCodeFormatter))
 	}
+	ignoreLinks, err := ignoreLinksFromFrontMatter(frontMatter)
+	if err != nil {
+		return fmt.Errorf("%v: %w", file.Name(), err)
+	}
 	tr := &transformer{
 		wrapped:   renderer,
 		sourceCtx: sourceCtx,
-		link:      f.link, cb: f.cb,
+		link:      f.link, cb: f.cb, gs: f.gs,
 		frontMatterLen: len(frontMatter),
+		ignoreLinks:    ignoreLinks,
 	}
 	if err := goldmark.New(
 		goldmark.WithExtensions(extension.GFM),
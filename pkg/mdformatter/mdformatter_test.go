@@ -7,6 +7,8 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/efficientgo/core/testutil"
@@ -179,3 +181,57 @@ func TestFormatBadlyFormattedGoCode(t *testing.T) {
 		testutil.Equals(t, string(exp), buf.String())
 	})
 }
+
+func TestFormat_FormatSingle_IgnoreLinks(t *testing.T) {
+	file, err := os.OpenFile("testdata/ignore_links.md", os.O_RDONLY, 0)
+	testutil.Ok(t, err)
+	defer file.Close()
+
+	f := New(context.Background())
+	f.link = &mockLinkTransformer{}
+
+	exp, err := os.ReadFile("testdata/ignore_links_transformed.md")
+	testutil.Ok(t, err)
+
+	t.Run("Format not formatted", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		testutil.Ok(t, f.Format(file, &buf))
+		testutil.Equals(t, string(exp), buf.String())
+	})
+
+	t.Run("Format formatted", func(t *testing.T) {
+		file2, err := os.OpenFile("testdata/ignore_links_transformed.md", os.O_RDONLY, 0)
+		testutil.Ok(t, err)
+		defer file2.Close()
+
+		buf := bytes.Buffer{}
+		testutil.Ok(t, f.Format(file2, &buf))
+		testutil.Equals(t, string(exp), buf.String())
+	})
+
+	t.Run("Inline comment", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "inline.md")
+		testutil.Ok(t, os.WriteFile(tmpFile, []byte("Text <!-- mdox-ignore-next-link --> [1](https://example.com/ignored) [2](https://example.com/checked)\n"), os.ModePerm))
+		file3, err := os.OpenFile(tmpFile, os.O_RDONLY, 0)
+		testutil.Ok(t, err)
+		defer file3.Close()
+
+		buf := bytes.Buffer{}
+		testutil.Ok(t, f.Format(file3, &buf))
+		testutil.Assert(t, strings.Contains(buf.String(), "[1](https://example.com/ignored)"), buf.String())
+		testutil.Assert(t, strings.Contains(buf.String(), "[2]($$-https://example.com/checked-"), buf.String())
+	})
+
+	t.Run("Missing closing comment", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "unclosed.md")
+		testutil.Ok(t, os.WriteFile(tmpFile, []byte("<!-- mdox-ignore-links-start -->\n\n[1](https://example.com)\n"), os.ModePerm))
+		file3, err := os.OpenFile(tmpFile, os.O_RDONLY, 0)
+		testutil.Ok(t, err)
+		defer file3.Close()
+
+		buf := bytes.Buffer{}
+		err = f.Format(file3, &buf)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "first formatting phase for "+tmpFile+": missing closing <!-- mdox-ignore-links-end --> comment", err.Error())
+	})
+}
@@ -17,6 +17,8 @@ import (
 const (
 	infoStringKeyExec     = "mdox-exec"
 	infoStringKeyExitCode = "mdox-expect-exit-code"
+
+	sectionKeyExec = "mdox-gen-exec"
 )
 
 var (
@@ -70,35 +72,81 @@ func (t *genCodeBlockTransformer) TransformCodeBlock(ctx mdformatter.SourceConte
 		if len(infoStringAttr) > 2 {
 			return nil, fmt.Errorf("got ambiguous attributes: %v. Expected format for %q is e.g ```text %q=<value> . Got info string %q", infoStringAttr, infoStringKeyExec, infoStringKeyExec, string(infoString))
 		}
-		execArgs, err := shellwords.NewParser().Parse(execCmd)
-		if err != nil {
-			return nil, fmt.Errorf("parsing exec command %v: %w", execCmd, err)
-		}
+		return execCommand(ctx, execCmd, infoStringAttr[infoStringKeyExitCode])
+	}
 
-		// Execute and render output.
-		b := bytes.Buffer{}
-		cmd := exec.CommandContext(ctx, execArgs[0], execArgs[1:]...)
-		cmd.Stderr = &b
-		cmd.Stdout = &b
-		if err := cmd.Run(); err != nil {
-			expectedCode, _ := strconv.Atoi(infoStringAttr[infoStringKeyExitCode])
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				if exitErr.ExitCode() != expectedCode {
-					return nil, fmt.Errorf("run %v, expected exit code %v, got %v, out: %v, error: %w", execCmd, expectedCode, exitErr.ExitCode(), b.String(), err)
-				}
-			} else {
-				return nil, fmt.Errorf("run %v, out: %v, error: %w", execCmd, b.String(), err)
-			}
+	panic("should never get here")
+}
+
+func (t *genCodeBlockTransformer) Close(ctx mdformatter.SourceContext) error { return nil }
+
+type genSectionTransformer struct{}
+
+// NewGenSectionTransformer returns mdformatter.GenSectionTransformer that replaces content of sections like:
+//
+//	<!-- mdox-gen-exec="<executable + arguments>" -->
+//	...
+//	<!-- mdox-gen-end -->
+//
+// with the markdown output of given command. Useful for content that can't live in code blocks, like tables.
+func NewGenSectionTransformer() *genSectionTransformer {
+	return &genSectionTransformer{}
+}
+
+func (t *genSectionTransformer) TransformGenSection(ctx mdformatter.SourceContext, directive []byte) ([]byte, error) {
+	fields, err := shellwords.NewParser().Parse(string(directive))
+	if err != nil {
+		return nil, fmt.Errorf("parsing directive %v: %w", string(directive), err)
+	}
+	attr := map[string]string{}
+	for _, field := range fields {
+		i := strings.Index(field, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("got %q without variable. Expected format is e.g <!-- %s=\"<value1>\" --> but got %s", field, sectionKeyExec, string(directive))
 		}
-		output := b.Bytes()
-		// Add newline to output if not present.
-		if !bytes.HasSuffix(output, newLineChar) {
-			output = append(output, newLineChar...)
+		switch field[:i] {
+		case sectionKeyExec, infoStringKeyExitCode:
+			attr[field[:i]] = field[i+1:]
+		default:
+			return nil, fmt.Errorf("unknown attribute %q in directive %s", field[:i], string(directive))
 		}
-		return output, nil
 	}
 
-	panic("should never get here")
+	execCmd, ok := attr[sectionKeyExec]
+	if !ok {
+		return nil, fmt.Errorf("expected %q attribute, got directive %s", sectionKeyExec, string(directive))
+	}
+	return execCommand(ctx, execCmd, attr[infoStringKeyExitCode])
 }
 
-func (t *genCodeBlockTransformer) Close(ctx mdformatter.SourceContext) error { return nil }
+func (t *genSectionTransformer) Close(ctx mdformatter.SourceContext) error { return nil }
+
+// execCommand runs given command and returns its combined output. Non-zero exit code fails, unless it matches expectedExitCode.
+func execCommand(ctx mdformatter.SourceContext, execCmd string, expectedExitCode string) ([]byte, error) {
+	execArgs, err := shellwords.NewParser().Parse(execCmd)
+	if err != nil {
+		return nil, fmt.Errorf("parsing exec command %v: %w", execCmd, err)
+	}
+
+	// Execute and render output.
+	b := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, execArgs[0], execArgs[1:]...)
+	cmd.Stderr = &b
+	cmd.Stdout = &b
+	if err := cmd.Run(); err != nil {
+		expectedCode, _ := strconv.Atoi(expectedExitCode)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if exitErr.ExitCode() != expectedCode {
+				return nil, fmt.Errorf("run %v, expected exit code %v, got %v, out: %v, error: %w", execCmd, expectedCode, exitErr.ExitCode(), b.String(), err)
+			}
+		} else {
+			return nil, fmt.Errorf("run %v, out: %v, error: %w", execCmd, b.String(), err)
+		}
+	}
+	output := b.Bytes()
+	// Add newline to output if not present.
+	if !bytes.HasSuffix(output, newLineChar) {
+		output = append(output, newLineChar...)
+	}
+	return output, nil
+}
@@ -14,7 +14,7 @@ import (
 )
 
 func TestFormat_FormatSingle_CodeBlockTransformer(t *testing.T) {
-	f := mdformatter.New(context.Background(), mdformatter.WithCodeBlockTransformer(NewCodeBlockTransformer()))
+	f := mdformatter.New(context.Background(), mdformatter.WithCodeBlockTransformer(NewCodeBlockTransformer()), mdformatter.WithGenSectionTransformer(NewGenSectionTransformer()))
 
 	exp, err := os.ReadFile("testdata/mdgen_formatted.md")
 	testutil.Ok(t, err)
@@ -39,3 +39,19 @@ func TestFormat_FormatSingle_CodeBlockTransformer(t *testing.T) {
 		testutil.Equals(t, string(exp), buf.String())
 	})
 }
+
+func TestFormat_GenSectionTransformer_MissingEnd(t *testing.T) {
+	f := mdformatter.New(context.Background(), mdformatter.WithGenSectionTransformer(NewGenSectionTransformer()))
+
+	file, err := os.CreateTemp(t.TempDir(), "*.md")
+	testutil.Ok(t, err)
+	defer file.Close()
+	_, err = file.WriteString("# Title\n\n<!-- mdox-gen-exec=\"bash ./testdata/out4.sh\" -->\n\nSome text.\n")
+	testutil.Ok(t, err)
+	_, err = file.Seek(0, 0)
+	testutil.Ok(t, err)
+
+	err = f.Format(file, &bytes.Buffer{})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `first formatting phase for `+file.Name()+`: missing closing <!-- mdox-gen-end --> comment for section <!-- mdox-gen-exec="bash ./testdata/out4.sh" -->`, err.Error())
+}
@@ -0,0 +1,5 @@
+#!/usr/bin/env bash
+
+echo "| Key | Type |"
+echo "|---|---|"
+echo "| \`bucket\` | \`string\` |"
@@ -5,9 +5,11 @@ package mdformatter
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/efficientgo/core/merrors"
 	"github.com/yuin/goldmark/ast"
@@ -33,11 +35,32 @@ type transformer struct {
 
 	link           LinkTransformer
 	cb             CodeBlockTransformer
+	gs             GenSectionTransformer
 	frontMatterLen int
+
+	// ignoreLinks are regexes of links to leave untouched, from front matter.
+	ignoreLinks []*regexp.Regexp
+	// ignoreNextLink is set by `<!-- mdox-ignore-next-link -->` comment.
+	ignoreNextLink bool
+	// ignoringLinks is set between `<!-- mdox-ignore-links-start -->` and `<!-- mdox-ignore-links-end -->` comments.
+	ignoringLinks bool
 }
 
+var (
+	genSectionPrefix = []byte("mdox-gen-")
+	genSectionEnd    = []byte("mdox-gen-end")
+)
+
+const (
+	ignoreNextLinkDirective   = "mdox-ignore-next-link"
+	ignoreLinksStartDirective = "mdox-ignore-links-start"
+	ignoreLinksEndDirective   = "mdox-ignore-links-end"
+	frontMatterKey            = "mdox"
+	frontMatterIgnoreLinksKey = "ignoreLinks"
+)
+
 func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
-	if t.link == nil && t.cb == nil {
+	if t.link == nil && t.cb == nil && t.gs == nil {
 		return t.wrapped.Render(w, source, node)
 	}
 
@@ -45,7 +68,18 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 		var err error
 		switch typedNode := n.(type) {
 		case *ast.HTMLBlock, *ast.RawHTML:
-			if !entering || t.link == nil {
+			if !entering {
+				return ast.WalkSkipChildren, nil
+			}
+			if t.gs != nil {
+				if directive := htmlComment(n, source); bytes.HasPrefix(directive, genSectionPrefix) && !bytes.Equal(directive, genSectionEnd) {
+					if err := t.transformGenSection(n, source, directive); err != nil {
+						return ast.WalkStop, err
+					}
+					return ast.WalkSkipChildren, nil
+				}
+			}
+			if t.link == nil {
 				return ast.WalkSkipChildren, nil
 			}
 
@@ -72,14 +106,20 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 			z := html.NewTokenizer(&b)
 			for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
 				token := z.Token()
+				if tt == html.CommentToken {
+					if err := t.linkDirective(strings.TrimSpace(token.Data)); err != nil {
+						return ast.WalkStop, err
+					}
+					out += token.String()
+					continue
+				}
 				switch token.Data {
 				case "img":
 					for i := range token.Attr {
 						if token.Attr[i].Key != "src" {
 							continue
 						}
-						t.sourceCtx.LineNumbers = getLinkLines(source, []byte(token.Attr[i].Val), t.frontMatterLen)
-						dest, err := t.link.TransformDestination(t.sourceCtx, []byte(token.Attr[i].Val))
+						dest, err := t.transformDestination(source, []byte(token.Attr[i].Val), nil)
 						if err != nil {
 							return ast.WalkStop, err
 						}
@@ -91,8 +131,7 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 						if token.Attr[i].Key != "href" {
 							continue
 						}
-						t.sourceCtx.LineNumbers = getLinkLines(source, []byte(token.Attr[i].Val), t.frontMatterLen)
-						dest, err := t.link.TransformDestination(t.sourceCtx, []byte(token.Attr[i].Val))
+						dest, err := t.transformDestination(source, []byte(token.Attr[i].Val), nil)
 						if err != nil {
 							return ast.WalkStop, err
 						}
@@ -116,8 +155,7 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 			if !entering || t.link == nil {
 				return ast.WalkSkipChildren, nil
 			}
-			t.sourceCtx.LineNumbers = getLinkLines(source, typedNode.Destination, t.frontMatterLen)
-			typedNode.Destination, err = t.link.TransformDestination(t.sourceCtx, typedNode.Destination)
+			typedNode.Destination, err = t.transformDestination(source, typedNode.Destination, typedNode.Text(source))
 			if err != nil {
 				return ast.WalkStop, err
 			}
@@ -125,8 +163,7 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 			if !entering || t.link == nil || typedNode.AutoLinkType != ast.AutoLinkURL {
 				return ast.WalkSkipChildren, nil
 			}
-			t.sourceCtx.LineNumbers = getLinkLines(source, typedNode.URL(source), t.frontMatterLen)
-			dest, err := t.link.TransformDestination(t.sourceCtx, typedNode.URL(source))
+			dest, err := t.transformDestination(source, typedNode.URL(source), typedNode.Label(source))
 			if err != nil {
 				return ast.WalkStop, err
 			}
@@ -142,8 +179,7 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 			if !entering || t.link == nil {
 				return ast.WalkSkipChildren, nil
 			}
-			t.sourceCtx.LineNumbers = getLinkLines(source, typedNode.Destination, t.frontMatterLen)
-			typedNode.Destination, err = t.link.TransformDestination(t.sourceCtx, typedNode.Destination)
+			typedNode.Destination, err = t.transformDestination(source, typedNode.Destination, typedNode.Text(source))
 			if err != nil {
 				return ast.WalkStop, err
 			}
@@ -166,9 +202,135 @@ func (t *transformer) Render(w io.Writer, source []byte, node ast.Node) error {
 	}); err != nil {
 		return err
 	}
+	if t.ignoringLinks {
+		return fmt.Errorf("missing closing <!-- %s --> comment", ignoreLinksEndDirective)
+	}
 	return t.wrapped.Render(w, source, node)
 }
 
+// linkDirective updates which links are ignored, if given HTML comment content is a link directive.
+func (t *transformer) linkDirective(comment string) error {
+	switch comment {
+	case ignoreNextLinkDirective:
+		t.ignoreNextLink = true
+	case ignoreLinksStartDirective:
+		if t.ignoringLinks {
+			return fmt.Errorf("nested <!-- %s --> comment", ignoreLinksStartDirective)
+		}
+		t.ignoringLinks = true
+	case ignoreLinksEndDirective:
+		if !t.ignoringLinks {
+			return fmt.Errorf("<!-- %s --> comment without opening <!-- %s --> comment", ignoreLinksEndDirective, ignoreLinksStartDirective)
+		}
+		t.ignoringLinks = false
+	}
+	return nil
+}
+
+// transformDestination passes link destination to LinkTransformer, unless link is ignored by comment or front matter.
+func (t *transformer) transformDestination(source []byte, dest []byte, text []byte) ([]byte, error) {
+	if t.ignoreNextLink {
+		t.ignoreNextLink = false
+		return dest, nil
+	}
+	if t.ignoringLinks {
+		return dest, nil
+	}
+	for _, re := range t.ignoreLinks {
+		if re.Match(dest) {
+			return dest, nil
+		}
+	}
+	t.sourceCtx.LineNumbers = getLinkLines(source, dest, t.frontMatterLen)
+	t.sourceCtx.LinkText = string(text)
+	return t.link.TransformDestination(t.sourceCtx, dest)
+}
+
+// ignoreLinksFromFrontMatter returns regexes of links to ignore from `mdox: {ignoreLinks: [...]}` front matter.
+func ignoreLinksFromFrontMatter(frontMatter map[string]interface{}) ([]*regexp.Regexp, error) {
+	m, ok := frontMatter[frontMatterKey]
+	if !ok {
+		return nil, nil
+	}
+	mdox, ok := m.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("front matter %q has to be a map, got %T", frontMatterKey, m)
+	}
+	l, ok := mdox[frontMatterIgnoreLinksKey]
+	if !ok {
+		return nil, nil
+	}
+	regexes, ok := l.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("front matter %v.%v has to be a list of regexes, got %T", frontMatterKey, frontMatterIgnoreLinksKey, l)
+	}
+
+	ignoreLinks := make([]*regexp.Regexp, 0, len(regexes))
+	for _, r := range regexes {
+		str, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("front matter %v.%v has to be a list of regexes, got %T element", frontMatterKey, frontMatterIgnoreLinksKey, r)
+		}
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return nil, fmt.Errorf("front matter %v.%v: %w", frontMatterKey, frontMatterIgnoreLinksKey, err)
+		}
+		ignoreLinks = append(ignoreLinks, re)
+	}
+	return ignoreLinks, nil
+}
+
+// transformGenSection replaces everything between given directive comment and closing `<!-- mdox-gen-end -->` comment
+// with content generated by GenSectionTransformer. Both comments are preserved.
+func (t *transformer) transformGenSection(n ast.Node, source []byte, directive []byte) error {
+	var end ast.Node
+	for s := n.NextSibling(); s != nil; s = s.NextSibling() {
+		if bytes.Equal(htmlComment(s, source), genSectionEnd) {
+			end = s
+			break
+		}
+	}
+	if end == nil {
+		return fmt.Errorf("missing closing <!-- %s --> comment for section <!-- %s -->", genSectionEnd, directive)
+	}
+
+	content, err := t.gs.TransformGenSection(t.sourceCtx, directive)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		return nil
+	}
+
+	// Remove old section content together with closing comment, we render it again below.
+	for s := n.NextSibling(); s != end; {
+		next := s.NextSibling()
+		n.Parent().RemoveChild(n.Parent(), s)
+		s = next
+	}
+	n.Parent().RemoveChild(n.Parent(), end)
+
+	b := bytes.Buffer{}
+	_, _ = b.WriteString("\n")
+	if n.HasBlankPreviousLines() {
+		_, _ = b.WriteString("\n")
+	}
+	_, _ = fmt.Fprintf(&b, "<!-- %s -->\n\n", directive)
+	_, _ = b.Write(content)
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		_, _ = b.WriteString("\n")
+	}
+	_, _ = fmt.Fprintf(&b, "\n<!-- %s -->\n", genSectionEnd)
+
+	repl := ast.NewString(b.Bytes())
+	repl.SetParent(n.Parent())
+	repl.SetPreviousSibling(n.PreviousSibling())
+	repl.SetNextSibling(n.NextSibling())
+	n.Parent().ReplaceChild(n.Parent(), n, repl)
+	n.SetNextSibling(repl.NextSibling()) // Make sure our loop can continue.
+	return nil
+}
+
 func (t *transformer) Close(ctx SourceContext) error {
 	errs := merrors.New()
 	if t.link != nil {
@@ -177,9 +339,40 @@ func (t *transformer) Close(ctx SourceContext) error {
 	if t.cb != nil {
 		errs.Add(t.cb.Close(ctx))
 	}
+	if t.gs != nil {
+		errs.Add(t.gs.Close(ctx))
+	}
 	return errs.Err()
 }
 
+// htmlComment returns trimmed content of HTML comment if given node is a block consisting of a single comment only.
+func htmlComment(n ast.Node, source []byte) []byte {
+	h, ok := n.(*ast.HTMLBlock)
+	if !ok || h.HTMLBlockType != ast.HTMLBlockType2 {
+		return nil
+	}
+
+	b := bytes.Buffer{}
+	for i := 0; i < h.Lines().Len(); i++ {
+		segment := h.Lines().At(i)
+		_, _ = b.Write(segment.Value(source))
+	}
+	if h.HasClosure() {
+		_, _ = b.Write(h.ClosureLine.Value(source))
+	}
+
+	c := bytes.TrimSpace(b.Bytes())
+	if !bytes.HasPrefix(c, []byte("<!--")) || !bytes.HasSuffix(c, []byte("-->")) {
+		return nil
+	}
+	c = bytes.TrimSpace(c[len("<!--") : len(c)-len("-->")])
+	if bytes.Contains(c, []byte("-->")) {
+		// Multiple comments in one block.
+		return nil
+	}
+	return c
+}
+
 func replaceContent(b *ast.BaseBlock, lastSegmentStop int, content []byte) {
 	s := text.NewSegments()
 	// NOTE(bwplotka): This feels like hack, because we pack all lines in single line. But it works (:
@@ -32,10 +32,10 @@ type Config struct {
 	Version int
 
 	// InputDir is a relative (to PWD) path that assumes input directory for markdown files and assets.
-	InputDir string `yaml:"inputDir"`
+	InputDir string `yaml:"inputDir" jsonschema:"required"`
 	// OutputDir is a relative (to PWD) output directory that we expect all files to land in. Typically that can be `content` dir
 	// which hugo uses as an input.
-	OutputDir string `yaml:"outputDir"`
+	OutputDir string `yaml:"outputDir" jsonschema:"required"`
 
 	// ExtraInputGlobs allows to bring files from outside of input dir.
 	ExtraInputGlobs []string `yaml:"extraInputGlobs"`
@@ -0,0 +1,42 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestGenerate(t *testing.T) {
+	type httpConfig struct {
+		IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+	}
+	b := bytes.Buffer{}
+	testutil.Ok(t, Generate(struct {
+		Name    string       `yaml:"name"`
+		HTTP    httpConfig   `yaml:"http_config"`
+		Targets []testTarget `yaml:"targets"`
+	}{Name: "bucket"}, &b))
+	testutil.Equals(t, "name: bucket\n"+
+		"http_config:\n"+
+		"    idle_conn_timeout: 0s\n"+
+		"targets: []\n", b.String())
+
+	err := Generate(struct {
+		HTTP struct {
+			Address string `yaml:"address,omitempty"`
+		} `yaml:"http"`
+	}{}, &b)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "invalid type: HTTP: omitempty is forbidden for config, but spotted on field 'Address'", err.Error())
+
+	err = Generate(struct {
+		HTTP *httpConfig `yaml:"http"`
+	}{}, &b)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "invalid type: HTTP: nil pointers are not allowed in configuration", err.Error())
+}
@@ -0,0 +1,255 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/structtag"
+	"gopkg.in/yaml.v3"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// YAMLRepresenter is implemented by config types with custom YAML unmarshalling. It returns value, which structure
+// describes the YAML form of the type, so JSON Schema can be generated for it.
+type YAMLRepresenter interface {
+	YAMLRepresentation() interface{}
+}
+
+// JSONSchema represents (subset of) JSON Schema draft 2020-12.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// GenerateJSONSchema writes JSON Schema (draft 2020-12) describing YAML form of given struct. Property names are taken
+// from `yaml` tags, descriptions from doc comments of the Go source (if available in module or GOPATH) and defaults
+// from given object. Additionally, `jsonschema` tag can mark fields as required and list allowed values e.g.
+//
+//	Type string `yaml:"type" jsonschema:"required,enum=sqlite,enum=none"`
+//
+// Types with custom YAML unmarshalling are described by their YAMLRepresenter implementation or allow any value.
+func GenerateJSONSchema(obj interface{}, w io.Writer) error {
+	s, err := NewJSONSchema(obj)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// NewJSONSchema returns JSON Schema (draft 2020-12) describing YAML form of given struct. See GenerateJSONSchema for details.
+func NewJSONSchema(obj interface{}) (*JSONSchema, error) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %v", v.Kind())
+	}
+
+	g := &schemaGen{root: v.Type(), defs: map[string]*JSONSchema{}, names: map[reflect.Type]string{}, docs: docCache{}}
+	root, err := g.structSchema(v)
+	if err != nil {
+		return nil, err
+	}
+	root.Schema = jsonSchemaDraft
+	root.Title = v.Type().Name()
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return root, nil
+}
+
+type schemaGen struct {
+	root  reflect.Type
+	defs  map[string]*JSONSchema
+	names map[reflect.Type]string
+	docs  docCache
+}
+
+func (g *schemaGen) structSchema(v reflect.Value) (*JSONSchema, error) {
+	s := &JSONSchema{
+		Type:                 "object",
+		Description:          g.docs.doc(v.Type(), ""),
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: false,
+	}
+	fields, err := structFields(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		fs, err := g.valueSchema(f.v, f.f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.f.Name, err)
+		}
+		fs.Description = g.docs.doc(f.owner, f.f.Name)
+
+		tags, err := structtag.Parse(string(f.f.Tag))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse tag %q: err: %w", f.f.Name, f.f.Tag, err)
+		}
+		if tag, err := tags.Get("jsonschema"); err == nil {
+			for _, o := range append([]string{tag.Name}, tag.Options...) {
+				switch {
+				case o == "required":
+					s.Required = append(s.Required, f.key)
+				case strings.HasPrefix(o, "enum="):
+					e, err := enumValue(f.f.Type, strings.TrimPrefix(o, "enum="))
+					if err != nil {
+						return nil, fmt.Errorf("%s: %w", f.f.Name, err)
+					}
+					fs.Enum = append(fs.Enum, e)
+				default:
+					return nil, fmt.Errorf("%s: unknown jsonschema tag option %q", f.f.Name, o)
+				}
+			}
+		}
+		s.Properties[f.key] = fs
+	}
+	return s, nil
+}
+
+// valueSchema returns schema for given value of type t. Value might be invalid (e.g. for collection elements), in which case
+// zero value is used.
+func (g *schemaGen) valueSchema(v reflect.Value, t reflect.Type) (*JSONSchema, error) {
+	if !v.IsValid() {
+		v = reflect.New(t).Elem()
+	}
+	if r, ok := v.Interface().(YAMLRepresenter); ok {
+		rv := reflect.ValueOf(r.YAMLRepresentation())
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		return g.valueSchema(rv, rv.Type())
+	}
+	if hasCustomYAML(t) {
+		// Anything goes.
+		return &JSONSchema{}, nil
+	}
+
+	s := &JSONSchema{}
+	switch t.Kind() {
+	case reflect.Ptr:
+		var elem reflect.Value
+		if !v.IsNil() {
+			elem = v.Elem()
+		}
+		return g.valueSchema(elem, t.Elem())
+	case reflect.Struct:
+		return g.structRef(v)
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+		if t == reflect.TypeOf(time.Duration(0)) {
+			// Durations are (un)marshalled as strings, e.g. "5m".
+			s.Type = "string"
+		}
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.Slice, reflect.Array:
+		items, err := g.valueSchema(reflect.Value{}, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s.Type = "array"
+		s.Items = items
+	case reflect.Map:
+		values, err := g.valueSchema(reflect.Value{}, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s.Type = "object"
+		s.AdditionalProperties = values
+	case reflect.Interface:
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %v", t)
+	}
+
+	if !v.IsZero() {
+		def, err := yamlValue(v)
+		if err != nil {
+			return nil, err
+		}
+		s.Default = def
+	}
+	return s, nil
+}
+
+// structRef registers definition of given struct type and returns reference to it. Defaults are taken from the value
+// the type was first seen with.
+func (g *schemaGen) structRef(v reflect.Value) (*JSONSchema, error) {
+	t := v.Type()
+	if t == g.root {
+		return &JSONSchema{Ref: "#"}, nil
+	}
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, exists := g.defs[name]; exists || name == "" {
+			name = strings.NewReplacer("/", ".", "*", "").Replace(t.String())
+		}
+		g.names[t] = name
+		// Register placeholder first, so recursive types terminate.
+		g.defs[name] = &JSONSchema{}
+
+		s, err := g.structSchema(v)
+		if err != nil {
+			return nil, err
+		}
+		*g.defs[name] = *s
+	}
+	return &JSONSchema{Ref: "#/$defs/" + name}, nil
+}
+
+// yamlValue returns given value as it would be represented in YAML.
+func yamlValue(v reflect.Value) (interface{}, error) {
+	b, err := yaml.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := yaml.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func enumValue(t reflect.Type, v string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.Atoi(v)
+	case reflect.Bool:
+		return strconv.ParseBool(v)
+	}
+	return v, nil
+}
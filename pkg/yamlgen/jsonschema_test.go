@@ -0,0 +1,83 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+type testSchemaConfig struct {
+	// Type of the storage.
+	Type    string            `yaml:"type" jsonschema:"required,enum=s3,enum=gcs"`
+	Retries int               `yaml:"retries" jsonschema:"enum=1,enum=2"`
+	Backend testCustomBackend `yaml:"backend"`
+	Nested  *testSchemaConfig `yaml:"nested"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+type testCustomBackend struct{ addr string }
+
+func (b *testCustomBackend) UnmarshalYAML(func(interface{}) error) error { return nil }
+
+func (testCustomBackend) YAMLRepresentation() interface{} { return testTarget{Address: "localhost:80"} }
+
+func TestGenerateJSONSchema(t *testing.T) {
+	b := bytes.Buffer{}
+	testutil.Ok(t, GenerateJSONSchema(testSchemaConfig{Type: "s3", Timeout: time.Minute}, &b))
+	testutil.Equals(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "testSchemaConfig",
+  "type": "object",
+  "properties": {
+    "backend": {
+      "$ref": "#/$defs/testTarget"
+    },
+    "nested": {
+      "$ref": "#"
+    },
+    "retries": {
+      "type": "integer",
+      "enum": [
+        1,
+        2
+      ]
+    },
+    "timeout": {
+      "type": "string",
+      "default": "1m0s"
+    },
+    "type": {
+      "description": "Type of the storage.",
+      "type": "string",
+      "enum": [
+        "s3",
+        "gcs"
+      ],
+      "default": "s3"
+    }
+  },
+  "required": [
+    "type"
+  ],
+  "additionalProperties": false,
+  "$defs": {
+    "testTarget": {
+      "type": "object",
+      "properties": {
+        "address": {
+          "description": "Address in host:port format.",
+          "type": "string",
+          "default": "localhost:80"
+        }
+      },
+      "additionalProperties": false
+    }
+  }
+}
+`, b.String())
+}
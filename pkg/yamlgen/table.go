@@ -0,0 +1,272 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/bwplotka/mdox/pkg/gfm"
+	"gopkg.in/yaml.v3"
+)
+
+type tableOptions struct {
+	headingLevel int
+}
+
+// TableOption is a functional option for GenerateTable.
+type TableOption func(*tableOptions)
+
+// WithHeadingLevel sets the markdown heading level used for each struct section. 3 by default.
+func WithHeadingLevel(level int) TableOption {
+	return func(o *tableOptions) {
+		o.headingLevel = level
+	}
+}
+
+// GenerateTable writes config reference for given struct in markdown. Each struct (given one and all nested ones)
+// gets its own section with GFM table containing YAML key, Go type, default and description of each field.
+// Defaults are taken from given object, descriptions from doc comments of the Go source (if available in
+// module or GOPATH). Nested structs are linked to their sections by anchor.
+func GenerateTable(obj interface{}, w io.Writer, opts ...TableOption) error {
+	o := tableOptions{headingLevel: 3}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.headingLevel < 1 || o.headingLevel > 6 {
+		return fmt.Errorf("heading level has to be between 1 and 6, got %v", o.headingLevel)
+	}
+
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct, got %v", v.Kind())
+	}
+
+	g := &tableGen{names: map[reflect.Type]string{}, docs: docCache{}}
+	if err := g.collect(v, v.Type().Name()); err != nil {
+		return err
+	}
+
+	for i, s := range g.sections {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := g.writeSection(w, s, o.headingLevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tableSection struct {
+	name   string
+	v      reflect.Value
+	fields []yamlField
+}
+
+type tableGen struct {
+	sections []*tableSection
+	// names holds section name of each visited struct type.
+	names map[reflect.Type]string
+	docs  docCache
+}
+
+// collect registers one section per struct type, for given struct value and all nested ones.
+func (g *tableGen) collect(v reflect.Value, name string) error {
+	return walkStructs(v, name, func(name string, v reflect.Value, fields []yamlField) error {
+		g.names[v.Type()] = g.uniqueName(v.Type(), name)
+		g.sections = append(g.sections, &tableSection{name: g.names[v.Type()], v: v, fields: fields})
+		return nil
+	})
+}
+
+func (g *tableGen) uniqueName(t reflect.Type, name string) string {
+	for _, n := range g.names {
+		if n != name {
+			continue
+		}
+		if pkg := t.PkgPath(); pkg != "" {
+			return pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+		}
+	}
+	return name
+}
+
+func (g *tableGen) writeSection(w io.Writer, s *tableSection, headingLevel int) error {
+	b := bytes.Buffer{}
+	_, _ = fmt.Fprintf(&b, "%s %s\n\n", strings.Repeat("#", headingLevel), s.name)
+	if doc := g.docs.doc(s.v.Type(), ""); doc != "" {
+		_, _ = fmt.Fprintf(&b, "%s\n\n", escapeMarkdown(doc))
+	}
+	_, _ = b.WriteString("| Key | Type | Default | Description |\n|-----|------|---------|-------------|\n")
+	for _, f := range s.fields {
+		def, err := defaultValue(f.v)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", s.name, f.f.Name, err)
+		}
+		_, _ = fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", f.key, g.typeName(f.f.Type), escapeCell(def), escapeCell(escapeMarkdown(g.docs.doc(f.owner, f.f.Name))))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// typeName returns Go type name, linking to sections of nested structs.
+func (g *tableGen) typeName(t reflect.Type) string {
+	elem := t
+	for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array || elem.Kind() == reflect.Map {
+		elem = elem.Elem()
+	}
+	name, ok := g.names[elem]
+	if !ok {
+		return "`" + t.String() + "`"
+	}
+	link := fmt.Sprintf("[%s](#%s)", name, gfm.HeaderID(name))
+	if prefix := strings.TrimSuffix(t.String(), elem.String()); prefix != "" {
+		// Code span, so brackets are not mistaken for reference links.
+		return "`" + prefix + "`" + link
+	}
+	return link
+}
+
+// defaultValue returns YAML representation of given value in flow style. Nested structs have their own sections, so
+// no default is printed for those.
+func defaultValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Struct && !hasCustomYAML(v.Type()) {
+		return "", nil
+	}
+
+	n := &yaml.Node{}
+	if err := n.Encode(v.Interface()); err != nil {
+		return "", err
+	}
+	setFlowStyle(n)
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return "`" + strings.TrimSpace(string(out)) + "`", nil
+}
+
+func setFlowStyle(n *yaml.Node) {
+	n.Style |= yaml.FlowStyle
+	for _, c := range n.Content {
+		setFlowStyle(c)
+	}
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "~", `\~`, "&", `\&`)
+
+// escapeMarkdown escapes markdown and HTML metacharacters of doc comment outside of its code spans, so it's rendered
+// as written e.g. with backslashes of regexes.
+func escapeMarkdown(s string) string {
+	parts := strings.Split(s, "`")
+	if len(parts)%2 == 0 {
+		// Unbalanced backticks, so there are no code spans.
+		return strings.ReplaceAll(markdownEscaper.Replace(s), "`", "\\`")
+	}
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = markdownEscaper.Replace(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
+
+// docCache caches doc comments by package path and "<Type>" or "<Type>.<Field>".
+type docCache map[string]map[string]string
+
+// doc returns doc comment of the given type (if field is empty) or its field, if Go source of type can be found.
+func (c docCache) doc(t reflect.Type, field string) string {
+	if t.PkgPath() == "" || t.Name() == "" {
+		return ""
+	}
+	docs, ok := c[t.PkgPath()]
+	if !ok {
+		// Best effort, without source docs we still can produce valid output.
+		docs, _ = packageDocs(t.PkgPath())
+		c[t.PkgPath()] = docs
+	}
+	key := t.Name()
+	if field != "" {
+		key += "." + field
+	}
+	return docs[key]
+}
+
+// packageDocs parses Go source of given package and returns doc comments of all struct types and their fields.
+func packageDocs(pkgPath string) (map[string]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := build.Import(pkgPath, wd, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), pkg.Dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, errors.New("no Go files found")
+	}
+
+	docs := map[string]string{}
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					if d := commentText(ts.Doc, ts.Comment); d != "" {
+						docs[ts.Name.Name] = d
+					} else if d := commentText(gd.Doc, nil); d != "" && len(gd.Specs) == 1 {
+						docs[ts.Name.Name] = d
+					}
+					for _, field := range st.Fields.List {
+						d := commentText(field.Doc, field.Comment)
+						for _, name := range field.Names {
+							docs[ts.Name.Name+"."+name.Name] = d
+						}
+					}
+				}
+			}
+		}
+	}
+	return docs, nil
+}
+
+func commentText(doc *ast.CommentGroup, comment *ast.CommentGroup) string {
+	if doc != nil {
+		return strings.Join(strings.Fields(doc.Text()), " ")
+	}
+	if comment != nil {
+		return strings.Join(strings.Fields(comment.Text()), " ")
+	}
+	return ""
+}
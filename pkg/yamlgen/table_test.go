@@ -0,0 +1,78 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+// testConfig is the root configuration.
+type testConfig struct {
+	// Name of the bucket.
+	Name     string            `yaml:"name"`
+	Insecure bool              `yaml:"insecure"` // Insecure disables TLS.
+	Labels   map[string]string `yaml:"labels"`
+	Timeout  time.Duration     `yaml:"timeout"`
+	// HTTP holds client options.
+	HTTP    testHTTPConfig   `yaml:"http_config"`
+	Targets []testTarget     `yaml:"targets"`
+	Inlined testInlineConfig `yaml:",inline"`
+	Skipped string           `yaml:"-"`
+
+	unexported int
+}
+
+type testHTTPConfig struct {
+	// IdleConnTimeout is the maximum amount of time an idle connection will remain idle | open.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+}
+
+type testTarget struct {
+	// Address in host:port format.
+	Address string `yaml:"address"`
+}
+
+type testInlineConfig struct {
+	// Region is inlined into root. Regions matching `eu-.*` e.g. eu\-west\-1 store *all* objects of <bucket>.
+	Region string `yaml:"region"`
+}
+
+func TestGenerateTable(t *testing.T) {
+	b := bytes.Buffer{}
+	testutil.Ok(t, GenerateTable(testConfig{
+		Name:    "bucket",
+		Labels:  map[string]string{"a": "b"},
+		Timeout: 5 * time.Second,
+		HTTP:    testHTTPConfig{IdleConnTimeout: 90 * time.Second},
+		Inlined: testInlineConfig{Region: "eu"},
+	}, &b, WithHeadingLevel(4)))
+
+	testutil.Equals(t, "#### testConfig\n\n"+
+		"testConfig is the root configuration.\n\n"+
+		"| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| `name` | `string` | `bucket` | Name of the bucket. |\n"+
+		"| `insecure` | `bool` | `false` | Insecure disables TLS. |\n"+
+		"| `labels` | `map[string]string` | `{a: b}` |  |\n"+
+		"| `timeout` | `time.Duration` | `5s` |  |\n"+
+		"| `http_config` | [testHTTPConfig](#testhttpconfig) |  | HTTP holds client options. |\n"+
+		"| `targets` | `[]`[testTarget](#testtarget) | `[]` |  |\n"+
+		"| `region` | `string` | `eu` | Region is inlined into root. Regions matching `eu-.*` e.g. eu\\\\-west\\\\-1 store \\*all\\* objects of \\<bucket\\>. |\n"+
+		"\n"+
+		"#### testHTTPConfig\n\n"+
+		"| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| `idle_conn_timeout` | `time.Duration` | `1m30s` | IdleConnTimeout is the maximum amount of time an idle connection will remain idle \\| open. |\n"+
+		"\n"+
+		"#### testTarget\n\n"+
+		"| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| `address` | `string` | `\"\"` | Address in host:port format. |\n", b.String())
+
+	testutil.NotOk(t, GenerateTable("not a struct", &b))
+}
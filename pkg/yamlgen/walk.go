@@ -0,0 +1,137 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package yamlgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/structtag"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlField is a struct field (un)marshalled to YAML.
+type yamlField struct {
+	key string
+	f   reflect.StructField
+	v   reflect.Value
+	// owner is the struct type field was declared in. Different than walked struct type for inlined structs.
+	owner reflect.Type
+}
+
+// walkStructs calls fn for given struct value and, depth first, for all nested structs reachable through its YAML
+// fields, once per struct type. Nested structs are named by their type name or, for anonymous structs, by name of the
+// parent and field. Structs of collection elements are passed as zero values, as their defaults are not known.
+func walkStructs(v reflect.Value, name string, fn func(name string, v reflect.Value, fields []yamlField) error) error {
+	visited := map[reflect.Type]struct{}{}
+	var walk func(v reflect.Value, name string) error
+	walk = func(v reflect.Value, name string) error {
+		if _, ok := visited[v.Type()]; ok {
+			return nil
+		}
+		visited[v.Type()] = struct{}{}
+
+		fields, err := structFields(v)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, v, fields); err != nil {
+			return err
+		}
+
+		for _, f := range fields {
+			nested, ok := nestedStruct(f.v)
+			if !ok {
+				continue
+			}
+			nestedName := nested.Type().Name()
+			if nestedName == "" {
+				// Anonymous struct.
+				nestedName = name + "." + f.f.Name
+			}
+			if err := walk(nested, nestedName); err != nil {
+				return fmt.Errorf("%s: %w", f.f.Name, err)
+			}
+		}
+		return nil
+	}
+	return walk(v, name)
+}
+
+// structFields returns all fields of struct that are (un)marshalled to YAML, including inlined ones.
+func structFields(v reflect.Value) ([]yamlField, error) {
+	var fields []yamlField
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tags, err := structtag.Parse(string(f.Tag))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse tag %q: err: %w", f.Name, f.Tag, err)
+		}
+
+		field := yamlField{key: strings.ToLower(f.Name), f: f, v: v.Field(i), owner: v.Type()}
+		inline := false
+		if tag, err := tags.Get("yaml"); err == nil {
+			if tag.Name == "-" {
+				continue
+			}
+			if tag.Name != "" {
+				field.key = tag.Name
+			}
+			inline = tag.HasOption("inline")
+		}
+
+		if inline {
+			nested, ok := nestedStruct(v.Field(i))
+			if !ok {
+				return nil, fmt.Errorf("%s: inline is only supported for structs", f.Name)
+			}
+			inlined, err := structFields(nested)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			fields = append(fields, inlined...)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// nestedStruct returns struct that should be walked separately, if any. Structs with custom YAML (un)marshalling
+// are treated as scalars.
+func nestedStruct(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || hasCustomYAML(t) {
+		return reflect.Value{}, false
+	}
+	if v.Type() == t {
+		return v, true
+	}
+	// Zero value of collection elements, defaults are not known for those.
+	return reflect.New(t).Elem(), true
+}
+
+func hasCustomYAML(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	for _, i := range []reflect.Type{
+		reflect.TypeOf((*yaml.Marshaler)(nil)).Elem(),
+		reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem(),
+	} {
+		if t.Implements(i) || pt.Implements(i) {
+			return true
+		}
+	}
+	return false
+}